@@ -1,18 +1,120 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"go-todo-api/internal/blob"
+	"go-todo-api/internal/config"
 	"go-todo-api/internal/db"
+	"go-todo-api/internal/handlers"
 	"go-todo-api/internal/routes"
+	"go-todo-api/internal/server"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/trash"
+	"go-todo-api/internal/utils"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"gorm.io/gorm"
 )
 
 func main() {
-	db.ConnectDatabase()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	var keys *utils.KeyManager
+	if cfg.Auth.JWT.IsEmpty() {
+		log.Println("Warning: no JWT signing key configured, using an ephemeral key")
+		keys, err = utils.NewEphemeralKeyManager()
+	} else {
+		keys, err = utils.LoadKeyManager(cfg.Auth.JWT)
+	}
+	if err != nil {
+		log.Fatalf("Error loading JWT signing keys: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var stores store.Stores
+	var checks map[string]handlers.HealthCheck
+	var database *gorm.DB
+	if cfg.Database.Driver == "memory" {
+		log.Println("Warning: using the in-memory store, data is lost on restart")
+		stores = store.NewMemoryStores()
+	} else {
+		database, err = db.ConnectWithRetry(ctx, cfg.Database)
+		if err != nil {
+			log.Fatalf("Error connecting to the database: %v", err)
+		}
 
-	r := routes.SetupRoutes()
+		migrator, err := db.NewMigrator(database)
+		if err != nil {
+			log.Fatalf("Error loading migrations: %v", err)
+		}
+		if cfg.Database.MigrateOnStart {
+			applied, err := migrator.Up(ctx)
+			if err != nil {
+				log.Fatalf("Error migrating the database: %v", err)
+			}
+			for _, m := range applied {
+				log.Printf("Applied migration %d_%s", m.Version, m.Name)
+			}
+		}
 
-	log.Println("Server running at http://localhost:8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatalf("Error starting the server: %v", err)
+		stores = store.NewGormStores(database, cfg.Database.QueryTimeout)
+		checks = handlers.DatabaseChecks(database, migrator)
 	}
+
+	blobs, err := blob.New(cfg.Attachments)
+	if err != nil {
+		log.Fatalf("Error opening attachment storage: %v", err)
+	}
+
+	health := handlers.NewHealthHandler(checks)
+	r := routes.SetupRoutes(routes.Dependencies{
+		Config: cfg,
+		Tokens: utils.NewTokenManager(keys, cfg.Auth),
+		Stores: stores,
+		Blobs:  blobs,
+		Health: health,
+	})
+
+	srv := server.New(cfg.Server, r)
+	srv.OnDrain(health.SetShuttingDown)
+	srv.AddWorker(trash.Purger(stores.Tasks, cfg.Trash))
+	srv.AddWorker(blob.Sweeper(stores.Attachments, blobs, cfg.Attachments.SweepInterval))
+	if database != nil {
+		srv.OnShutdown(func(ctx context.Context) error {
+			sqlDB, err := database.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		})
+	}
+
+	scheme := "http"
+	if cfg.Server.TLSCertFile != "" {
+		scheme = "https"
+	}
+	log.Printf("Server running at %s://%s", scheme, cfg.Server.Addr)
+
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("Error running the server: %v", err)
+	}
+
+	log.Println("Server stopped")
 }
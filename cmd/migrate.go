@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/db"
+	"go-todo-api/internal/migrate"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: migrate up|down|status [flags]"
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+
+	if cfg.Database.Driver == "memory" {
+		fmt.Fprintln(os.Stderr, "The memory driver has no schema to migrate")
+		return 1
+	}
+
+	ctx := context.Background()
+
+	database, err := db.ConnectWithRetry(ctx, cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to the database: %v\n", err)
+		return 1
+	}
+	if sqlDB, err := database.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading migrations: %v\n", err)
+		return 1
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("Applied %d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+
+	case "down":
+		m, err := migrator.Down(ctx)
+		if errors.Is(err, migrate.ErrNoMigrations) {
+			fmt.Println("No migrations to roll back")
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Rolled back %d_%s\n", m.Version, m.Name)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		w.Flush()
+	}
+
+	return 0
+}
@@ -0,0 +1,39 @@
+// Package blob stores the contents of attachments, on the local disk or in
+// an S3-compatible bucket.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go-todo-api/internal/config"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs by key. Keys are slash-separated paths chosen by the
+// caller, such as "tasks/12/3f2a9c0d".
+type Store interface {
+	// Put stores the size bytes read from r under key.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under key for reading. It returns
+	// ErrNotFound when there is none. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the store selected by cfg.Storage.
+func New(cfg config.AttachmentConfig) (Store, error) {
+	switch cfg.Storage {
+	case "local":
+		return NewLocalStore(cfg.Dir)
+	case "s3":
+		return NewS3Store(cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown attachment storage %q", cfg.Storage)
+	}
+}
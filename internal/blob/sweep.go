@@ -0,0 +1,83 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// sweepBatchSize bounds the number of blobs deleted per round.
+const sweepBatchSize = 100
+
+// Orphans is the queue of blobs whose attachments have been deleted.
+type Orphans interface {
+	// OrphanedBlobs returns up to limit queued keys.
+	OrphanedBlobs(ctx context.Context, limit int) ([]string, error)
+	// ForgetBlobs removes keys from the queue.
+	ForgetBlobs(ctx context.Context, keys []string) error
+}
+
+// Sweeper returns a worker that deletes the queued blobs from blobs. It
+// runs once at start and then every interval.
+func Sweeper(orphans Orphans, blobs Store, interval time.Duration) func(context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			Sweep(ctx, orphans, blobs)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// Sweep deletes the queued blobs until the queue is empty. Blobs that
+// can't be deleted stay queued; failures are logged and retried on the
+// next run.
+func Sweep(ctx context.Context, orphans Orphans, blobs Store) {
+	var deleted int
+	for {
+		keys, err := orphans.OrphanedBlobs(ctx, sweepBatchSize)
+		if err == nil && len(keys) > 0 {
+			var done []string
+			done, err = deleteBlobs(ctx, blobs, keys)
+			deleted += len(done)
+			err = errors.Join(err, orphans.ForgetBlobs(ctx, done))
+		}
+
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Error deleting attachment contents: %v", err)
+			}
+			break
+		}
+		if len(keys) < sweepBatchSize {
+			break
+		}
+	}
+
+	if deleted > 0 {
+		log.Printf("Deleted the contents of %d attachments", deleted)
+	}
+}
+
+// deleteBlobs deletes the given blobs and returns the keys of those that
+// are gone.
+func deleteBlobs(ctx context.Context, blobs Store, keys []string) ([]string, error) {
+	done := make([]string, 0, len(keys))
+	var errs []error
+	for _, key := range keys {
+		if err := blobs.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		done = append(done, key)
+	}
+	return done, errors.Join(errs...)
+}
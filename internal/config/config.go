@@ -0,0 +1,533 @@
+// Package config loads the application settings.
+//
+// Every setting has a default and can be overridden, from lowest to highest
+// precedence, by a YAML or TOML config file, an environment variable and a
+// command line flag. The file is selected with -config or CONFIG_FILE.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Auth        AuthConfig
+	Trash       TrashConfig
+	Attachments AttachmentConfig
+}
+
+type ServerConfig struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	TLSCertFile       string
+	TLSKeyFile        string
+}
+
+// DatabaseConfig selects the storage backend: postgres, sqlite, or memory,
+// which keeps everything in process and loses it on restart.
+type DatabaseConfig struct {
+	Driver string
+	// URL is a postgres:// connection URL. It takes precedence over the
+	// individual connection settings.
+	URL      string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	// Path is the SQLite database file, or ":memory:" for a private
+	// in-memory database.
+	Path        string
+	BusyTimeout time.Duration
+	// MigrateOnStart applies pending migrations before serving.
+	MigrateOnStart bool
+
+	// ConnectTimeout bounds how long startup keeps retrying to connect.
+	ConnectTimeout  time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// QueryTimeout bounds each store call. Zero disables it.
+	QueryTimeout time.Duration
+}
+
+type AuthConfig struct {
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	BcryptCost      int
+	Cookie          CookieConfig
+	JWT             JWTConfig
+}
+
+// CookieConfig controls the attributes of the refresh token cookie, which is
+// always HTTP-only.
+type CookieConfig struct {
+	Domain   string
+	Path     string
+	Secure   bool
+	SameSite string
+}
+
+// JWTConfig describes where signing keys are loaded from. The active key is
+// either an HS256 secret or a PEM private key; retiring keys are only used
+// to verify tokens signed before a rotation.
+type JWTConfig struct {
+	KeyID            string
+	Secret           string
+	SecretFile       string
+	PrivateKeyFile   string
+	RetiringSecrets  []string
+	RetiringKeyFiles []string
+}
+
+// IsEmpty reports whether no active signing key has been configured.
+func (c JWTConfig) IsEmpty() bool {
+	return c.Secret == "" && c.SecretFile == "" && c.PrivateKeyFile == ""
+}
+
+// TrashConfig controls how long deleted tasks can be restored before the
+// background purge removes them for good.
+type TrashConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// AttachmentConfig limits uploaded files and selects where their contents
+// are stored: local, a directory on disk, or s3, a bucket of any
+// S3-compatible service.
+type AttachmentConfig struct {
+	// MaxSize is the largest accepted file, in bytes.
+	MaxSize int
+	// AllowedTypes lists the accepted MIME types, which are detected from
+	// the file contents.
+	AllowedTypes []string
+	Storage      string
+	Dir          string
+	S3           S3Config
+	// SweepInterval is how often the contents of deleted attachments are
+	// removed from storage.
+	SweepInterval time.Duration
+}
+
+// S3Config addresses a bucket with path-style requests, which every
+// S3-compatible service supports.
+type S3Config struct {
+	// Endpoint is the base URL of the service, such as
+	// https://s3.eu-west-1.amazonaws.com or http://localhost:9000.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:         "postgres",
+			Host:           "localhost",
+			Port:           "5432",
+			User:           "postgres",
+			Name:           "tasks_db",
+			SSLMode:        "disable",
+			Path:           "tasks.db",
+			BusyTimeout:    5 * time.Second,
+			MigrateOnStart: true,
+
+			ConnectTimeout:  30 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			QueryTimeout:    5 * time.Second,
+		},
+		Auth: AuthConfig{
+			Issuer:          "go-todo-api",
+			AccessTokenTTL:  30 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+			BcryptCost:      bcrypt.DefaultCost,
+			Cookie: CookieConfig{
+				Path:     "/",
+				SameSite: "lax",
+			},
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Attachments: AttachmentConfig{
+			MaxSize: 10 << 20,
+			AllowedTypes: []string{
+				"image/png", "image/jpeg", "image/gif", "image/webp",
+				"application/pdf", "text/plain",
+			},
+			Storage:       "local",
+			Dir:           "attachments",
+			S3:            S3Config{Region: "us-east-1"},
+			SweepInterval: time.Minute,
+		},
+	}
+}
+
+// setting binds a config file key to its environment variable. The flag
+// name is the key with dots and underscores replaced by dashes.
+type setting struct {
+	key   string
+	env   string
+	usage string
+	field func(*Config) interface{}
+}
+
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+var settings = []setting{
+	{"server.addr", "SERVER_ADDR", "HTTP listen address", func(c *Config) interface{} { return &c.Server.Addr }},
+	{"server.read_timeout", "SERVER_READ_TIMEOUT", "maximum duration for reading a request", func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+	{"server.read_header_timeout", "SERVER_READ_HEADER_TIMEOUT", "maximum duration for reading request headers", func(c *Config) interface{} { return &c.Server.ReadHeaderTimeout }},
+	{"server.write_timeout", "SERVER_WRITE_TIMEOUT", "maximum duration for writing a response", func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+	{"server.idle_timeout", "SERVER_IDLE_TIMEOUT", "how long keep-alive connections stay idle", func(c *Config) interface{} { return &c.Server.IdleTimeout }},
+	{"server.shutdown_timeout", "SERVER_SHUTDOWN_TIMEOUT", "how long to drain connections on shutdown", func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+	{"server.tls_cert_file", "SERVER_TLS_CERT_FILE", "PEM certificate to serve HTTPS", func(c *Config) interface{} { return &c.Server.TLSCertFile }},
+	{"server.tls_key_file", "SERVER_TLS_KEY_FILE", "PEM private key to serve HTTPS", func(c *Config) interface{} { return &c.Server.TLSKeyFile }},
+
+	{"database.driver", "DB_DRIVER", "storage backend: postgres, sqlite or memory", func(c *Config) interface{} { return &c.Database.Driver }},
+	{"database.url", "DATABASE_URL", "postgres connection URL, overrides host, port, user, password and name", func(c *Config) interface{} { return &c.Database.URL }},
+	{"database.host", "DB_HOST", "database host", func(c *Config) interface{} { return &c.Database.Host }},
+	{"database.port", "DB_PORT", "database port", func(c *Config) interface{} { return &c.Database.Port }},
+	{"database.user", "DB_USER", "database user", func(c *Config) interface{} { return &c.Database.User }},
+	{"database.password", "DB_PASSWORD", "database password", func(c *Config) interface{} { return &c.Database.Password }},
+	{"database.name", "DB_NAME", "database name", func(c *Config) interface{} { return &c.Database.Name }},
+	{"database.sslmode", "DB_SSLMODE", "postgres sslmode", func(c *Config) interface{} { return &c.Database.SSLMode }},
+	{"database.path", "DB_PATH", "SQLite database file, or :memory:", func(c *Config) interface{} { return &c.Database.Path }},
+	{"database.busy_timeout", "DB_BUSY_TIMEOUT", "how long SQLite waits for a locked database", func(c *Config) interface{} { return &c.Database.BusyTimeout }},
+	{"database.migrate_on_start", "DB_MIGRATE_ON_START", "apply pending migrations at startup", func(c *Config) interface{} { return &c.Database.MigrateOnStart }},
+	{"database.connect_timeout", "DB_CONNECT_TIMEOUT", "how long to retry connecting at startup", func(c *Config) interface{} { return &c.Database.ConnectTimeout }},
+	{"database.max_open_conns", "DB_MAX_OPEN_CONNS", "maximum open connections, 0 for unlimited", func(c *Config) interface{} { return &c.Database.MaxOpenConns }},
+	{"database.max_idle_conns", "DB_MAX_IDLE_CONNS", "maximum idle connections kept in the pool", func(c *Config) interface{} { return &c.Database.MaxIdleConns }},
+	{"database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", "maximum time a connection is reused, 0 for forever", func(c *Config) interface{} { return &c.Database.ConnMaxLifetime }},
+	{"database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", "maximum time a connection stays idle, 0 for forever", func(c *Config) interface{} { return &c.Database.ConnMaxIdleTime }},
+	{"database.query_timeout", "DB_QUERY_TIMEOUT", "timeout of each database call, 0 to disable", func(c *Config) interface{} { return &c.Database.QueryTimeout }},
+
+	{"auth.issuer", "TOKEN_ISSUER", "issuer claim of generated tokens", func(c *Config) interface{} { return &c.Auth.Issuer }},
+	{"auth.access_token_ttl", "ACCESS_TOKEN_TTL", "access token lifetime", func(c *Config) interface{} { return &c.Auth.AccessTokenTTL }},
+	{"auth.refresh_token_ttl", "REFRESH_TOKEN_TTL", "refresh token lifetime", func(c *Config) interface{} { return &c.Auth.RefreshTokenTTL }},
+	{"auth.bcrypt_cost", "BCRYPT_COST", "bcrypt cost for password hashes", func(c *Config) interface{} { return &c.Auth.BcryptCost }},
+
+	{"auth.cookie.domain", "COOKIE_DOMAIN", "refresh cookie domain", func(c *Config) interface{} { return &c.Auth.Cookie.Domain }},
+	{"auth.cookie.path", "COOKIE_PATH", "refresh cookie path", func(c *Config) interface{} { return &c.Auth.Cookie.Path }},
+	{"auth.cookie.secure", "COOKIE_SECURE", "only send the refresh cookie over HTTPS", func(c *Config) interface{} { return &c.Auth.Cookie.Secure }},
+	{"auth.cookie.same_site", "COOKIE_SAMESITE", "refresh cookie SameSite mode (lax, strict or none)", func(c *Config) interface{} { return &c.Auth.Cookie.SameSite }},
+
+	{"auth.jwt.key_id", "JWT_KEY_ID", "overrides the kid of the active signing key", func(c *Config) interface{} { return &c.Auth.JWT.KeyID }},
+	{"auth.jwt.secret", "JWT_SECRET", "HS256 signing secret", func(c *Config) interface{} { return &c.Auth.JWT.Secret }},
+	{"auth.jwt.secret_file", "JWT_SECRET_FILE", "file containing the HS256 signing secret", func(c *Config) interface{} { return &c.Auth.JWT.SecretFile }},
+	{"auth.jwt.private_key_file", "JWT_PRIVATE_KEY_FILE", "PEM private key for RS256 or EdDSA", func(c *Config) interface{} { return &c.Auth.JWT.PrivateKeyFile }},
+	{"auth.jwt.retiring_secrets", "JWT_RETIRING_SECRETS", "comma-separated HS256 secrets accepted for verification", func(c *Config) interface{} { return &c.Auth.JWT.RetiringSecrets }},
+	{"auth.jwt.retiring_key_files", "JWT_RETIRING_KEY_FILES", "comma-separated PEM files accepted for verification", func(c *Config) interface{} { return &c.Auth.JWT.RetiringKeyFiles }},
+
+	{"trash.retention", "TRASH_RETENTION", "how long deleted tasks stay in the trash", func(c *Config) interface{} { return &c.Trash.Retention }},
+	{"trash.purge_interval", "TRASH_PURGE_INTERVAL", "how often expired tasks are purged from the trash", func(c *Config) interface{} { return &c.Trash.PurgeInterval }},
+
+	{"attachments.max_size", "ATTACHMENTS_MAX_SIZE", "largest accepted attachment, in bytes", func(c *Config) interface{} { return &c.Attachments.MaxSize }},
+	{"attachments.allowed_types", "ATTACHMENTS_ALLOWED_TYPES", "comma-separated MIME types accepted as attachments", func(c *Config) interface{} { return &c.Attachments.AllowedTypes }},
+	{"attachments.storage", "ATTACHMENTS_STORAGE", "attachment storage: local or s3", func(c *Config) interface{} { return &c.Attachments.Storage }},
+	{"attachments.dir", "ATTACHMENTS_DIR", "directory of the local attachment storage", func(c *Config) interface{} { return &c.Attachments.Dir }},
+	{"attachments.sweep_interval", "ATTACHMENTS_SWEEP_INTERVAL", "how often the contents of deleted attachments are removed", func(c *Config) interface{} { return &c.Attachments.SweepInterval }},
+	{"attachments.s3.endpoint", "S3_ENDPOINT", "base URL of the S3-compatible service", func(c *Config) interface{} { return &c.Attachments.S3.Endpoint }},
+	{"attachments.s3.region", "S3_REGION", "region requests are signed for", func(c *Config) interface{} { return &c.Attachments.S3.Region }},
+	{"attachments.s3.bucket", "S3_BUCKET", "bucket attachments are stored in", func(c *Config) interface{} { return &c.Attachments.S3.Bucket }},
+	{"attachments.s3.access_key_id", "S3_ACCESS_KEY_ID", "S3 access key ID", func(c *Config) interface{} { return &c.Attachments.S3.AccessKeyID }},
+	{"attachments.s3.secret_access_key", "S3_SECRET_ACCESS_KEY", "S3 secret access key", func(c *Config) interface{} { return &c.Attachments.S3.SecretAccessKey }},
+}
+
+// Load builds the configuration from defaults, the optional config file,
+// the environment and args, then validates it. It returns flag.ErrHelp when
+// args ask for usage.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("go-todo-api", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flagName(), fmt.Sprintf("%s (env %s)", s.usage, s.env), func(value string) error {
+			flagValues[s.key] = value
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := assign(s.field(cfg), value); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := flagValues[s.key]; ok {
+			if err := assign(s.field(cfg), value); err != nil {
+				return nil, fmt.Errorf("-%s: %w", s.flagName(), err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var document map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.Unmarshal(data, &document)
+	default:
+		return fmt.Errorf("%s: config file must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := map[string]interface{}{}
+	flatten("", document, values)
+
+	byKey := map[string]setting{}
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := assign(s.field(c), values[key]); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+func flatten(prefix string, node map[string]interface{}, out map[string]interface{}) {
+	for key, value := range node {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok {
+			flatten(key, child, out)
+			continue
+		}
+		out[key] = value
+	}
+}
+
+// assign parses raw, a string or a value decoded from the config file, into
+// the field pointed to by target.
+func assign(target interface{}, raw interface{}) error {
+	if list, ok := raw.([]interface{}); ok {
+		field, ok := target.(*[]string)
+		if !ok {
+			return errors.New("unexpected list")
+		}
+		*field = nil
+		for _, item := range list {
+			*field = append(*field, fmt.Sprint(item))
+		}
+		return nil
+	}
+
+	value := fmt.Sprint(raw)
+
+	switch field := target.(type) {
+	case *string:
+		*field = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*field = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*field = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*field = d
+	case *[]string:
+		*field = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*field = append(*field, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported field type %T", target)
+	}
+
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr must not be empty")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""),
+		"server.tls_cert_file and server.tls_key_file must be set together")
+
+	switch c.Database.Driver {
+	case "memory":
+	case "postgres":
+		if c.Database.URL != "" {
+			u, err := url.Parse(c.Database.URL)
+			check(err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql"),
+				"database.url must be a postgres:// or postgresql:// URL")
+		} else {
+			check(c.Database.Host != "", "database.host must not be empty")
+			check(c.Database.Name != "", "database.name must not be empty")
+			if _, err := strconv.Atoi(c.Database.Port); err != nil {
+				errs = append(errs, fmt.Errorf("database.port must be a number, got %q", c.Database.Port))
+			}
+		}
+		switch c.Database.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			errs = append(errs, fmt.Errorf("database.sslmode must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", c.Database.SSLMode))
+		}
+	case "sqlite":
+		check(c.Database.Path != "", "database.path must not be empty")
+		check(c.Database.BusyTimeout >= 0, "database.busy_timeout must not be negative")
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be postgres, sqlite or memory, got %q", c.Database.Driver))
+	}
+	check(c.Database.ConnectTimeout >= 0, "database.connect_timeout must not be negative")
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max_idle_conns must not be negative")
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn_max_lifetime must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn_max_idle_time must not be negative")
+	check(c.Database.QueryTimeout >= 0, "database.query_timeout must not be negative")
+
+	check(c.Auth.Issuer != "", "auth.issuer must not be empty")
+	check(c.Auth.AccessTokenTTL > 0, "auth.access_token_ttl must be positive")
+	check(c.Auth.RefreshTokenTTL > 0, "auth.refresh_token_ttl must be positive")
+	check(c.Auth.BcryptCost >= bcrypt.MinCost && c.Auth.BcryptCost <= bcrypt.MaxCost,
+		"auth.bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+
+	switch c.Auth.Cookie.SameSite {
+	case "lax", "strict":
+	case "none":
+		check(c.Auth.Cookie.Secure, "auth.cookie.same_site none requires auth.cookie.secure")
+	default:
+		errs = append(errs, fmt.Errorf("auth.cookie.same_site must be lax, strict or none, got %q", c.Auth.Cookie.SameSite))
+	}
+
+	jwt := c.Auth.JWT
+	check(jwt.PrivateKeyFile == "" || (jwt.Secret == "" && jwt.SecretFile == ""),
+		"auth.jwt.private_key_file cannot be combined with an HS256 secret")
+	check(jwt.Secret == "" || jwt.SecretFile == "", "auth.jwt.secret and auth.jwt.secret_file are mutually exclusive")
+
+	check(c.Trash.Retention > 0, "trash.retention must be positive")
+	check(c.Trash.PurgeInterval > 0, "trash.purge_interval must be positive")
+
+	check(c.Attachments.MaxSize > 0, "attachments.max_size must be positive")
+	check(len(c.Attachments.AllowedTypes) > 0, "attachments.allowed_types must not be empty")
+	check(c.Attachments.SweepInterval > 0, "attachments.sweep_interval must be positive")
+	switch c.Attachments.Storage {
+	case "local":
+		check(c.Attachments.Dir != "", "attachments.dir must not be empty")
+	case "s3":
+		s3 := c.Attachments.S3
+		u, err := url.Parse(s3.Endpoint)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"attachments.s3.endpoint must be an http:// or https:// URL")
+		check(s3.Region != "", "attachments.s3.region must not be empty")
+		check(s3.Bucket != "", "attachments.s3.bucket must not be empty")
+		check(s3.AccessKeyID != "" && s3.SecretAccessKey != "",
+			"attachments.s3.access_key_id and attachments.s3.secret_access_key must be set")
+	default:
+		errs = append(errs, fmt.Errorf("attachments.storage must be local or s3, got %q", c.Attachments.Storage))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+database:
+  host: file-host
+  name: file-db
+auth:
+  access_token_ttl: 5m
+  jwt:
+    retiring_secrets: [one, two]
+`)
+
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("ACCESS_TOKEN_TTL", "10m")
+
+	cfg, err := Load([]string{"-config", path, "-auth-access-token-ttl", "15m"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.Server.Addr)
+	assert.Equal(t, "file-db", cfg.Database.Name)
+	assert.Equal(t, "env-host", cfg.Database.Host)
+	assert.Equal(t, 15*time.Minute, cfg.Auth.AccessTokenTTL)
+	assert.Equal(t, []string{"one", "two"}, cfg.Auth.JWT.RetiringSecrets)
+}
+
+func TestLoadTOMLFromEnv(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[auth]
+bcrypt_cost = 12
+
+[auth.cookie]
+secure = true
+same_site = "none"
+`)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("JWT_RETIRING_KEY_FILES", "a.pem, b.pem")
+
+	cfg, err := Load(nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 12, cfg.Auth.BcryptCost)
+	assert.True(t, cfg.Auth.Cookie.Secure)
+	assert.Equal(t, "none", cfg.Auth.Cookie.SameSite)
+	assert.Equal(t, []string{"a.pem", "b.pem"}, cfg.Auth.JWT.RetiringKeyFiles)
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  adr: \":9000\"\n")
+
+	_, err := Load([]string{"-config", path})
+	assert.ErrorContains(t, err, `unknown setting "server.adr"`)
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	t.Setenv("BCRYPT_COST", "many")
+
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "BCRYPT_COST")
+
+	_, err = Load([]string{"-auth-refresh-token-ttl", "week"})
+	assert.Error(t, err)
+
+	_, err = Load([]string{"-help"})
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = ""
+	cfg.Auth.AccessTokenTTL = 0
+	cfg.Auth.Cookie.SameSite = "none"
+	cfg.Auth.JWT.Secret = "secret"
+	cfg.Auth.JWT.PrivateKeyFile = "key.pem"
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "server.addr")
+	assert.ErrorContains(t, err, "auth.access_token_ttl")
+	assert.ErrorContains(t, err, "auth.cookie.secure")
+	assert.ErrorContains(t, err, "auth.jwt.private_key_file")
+}
+
+func TestValidateDatabaseDriver(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Driver = "memory"
+	cfg.Database.Host = ""
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Database.Driver = "sqlite"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Database.Path = ""
+	assert.ErrorContains(t, cfg.Validate(), "database.path")
+
+	cfg.Database.Driver = "postgres"
+	cfg.Database.URL = "postgres://db.internal/tasks"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Database.URL = "db.internal:5432"
+	cfg.Database.SSLMode = "on"
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "database.url")
+	assert.ErrorContains(t, err, "database.sslmode")
+
+	cfg.Database.Driver = "mysql"
+	assert.ErrorContains(t, cfg.Validate(), "database.driver")
+}
+
+func TestValidateTrash(t *testing.T) {
+	cfg := Default()
+	cfg.Trash.Retention = 0
+	cfg.Trash.PurgeInterval = -time.Minute
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "trash.retention")
+	assert.ErrorContains(t, err, "trash.purge_interval")
+}
+
+func TestValidateAttachments(t *testing.T) {
+	cfg := Default()
+	cfg.Attachments.MaxSize = 0
+	cfg.Attachments.AllowedTypes = nil
+	cfg.Attachments.Storage = "s3"
+	cfg.Attachments.S3.Endpoint = "localhost:9000"
+
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "attachments.max_size")
+	assert.ErrorContains(t, err, "attachments.allowed_types")
+	assert.ErrorContains(t, err, "attachments.s3.endpoint")
+	assert.ErrorContains(t, err, "attachments.s3.bucket")
+	assert.ErrorContains(t, err, "attachments.s3.access_key_id")
+
+	cfg = Default()
+	cfg.Attachments.Storage = "s3"
+	cfg.Attachments.S3 = S3Config{
+		Endpoint:        "http://localhost:9000",
+		Region:          "us-east-1",
+		Bucket:          "attachments",
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio123",
+	}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Attachments.Storage = "ftp"
+	assert.ErrorContains(t, cfg.Validate(), "attachments.storage")
+}
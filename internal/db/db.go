@@ -1,38 +1,100 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"time"
 
-	"go-todo-api/internal/models"
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/migrate"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-func ConnectDatabase() {
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
+// ConnectDatabase opens the SQL database selected by cfg.Driver.
+func ConnectDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case "postgres":
+		dsn, err := PostgresDSN(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dialector = postgres.Open(dsn)
+	case "sqlite":
+		dialector = sqlite.Open(SQLiteDSN(cfg))
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
 
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		host, user, password, dbname, port,
-	)
+	database, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the database: %w", err)
+	}
 
-	database, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	sqlDB, err := database.DB()
 	if err != nil {
-		log.Fatal("Error connecting to the database:", err)
+		return nil, err
+	}
+
+	if cfg.Driver == "sqlite" && IsMemorySQLite(cfg.Path) {
+		// Every connection to :memory: opens a new empty database, so a
+		// single connection must be kept open for good.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+	} else {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	}
+
+	return database, nil
+}
+
+const (
+	initialRetryDelay = 250 * time.Millisecond
+	maxRetryDelay     = 5 * time.Second
+)
+
+// ConnectWithRetry calls ConnectDatabase until it succeeds, backing off
+// exponentially, for up to cfg.ConnectTimeout. It gives up early if ctx is
+// cancelled.
+func ConnectWithRetry(ctx context.Context, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	delay := initialRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		database, err := ConnectDatabase(cfg)
+		if err == nil {
+			return database, nil
+		}
+
+		if time.Now().Add(delay).After(deadline) {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		log.Printf("Database not ready (attempt %d), retrying in %s: %v", attempt, delay, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(ctx.Err(), err)
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, maxRetryDelay)
 	}
+}
 
-	err = database.AutoMigrate(&models.Task{}, &models.User{})
+// NewMigrator returns a migrator using the scripts for the database's dialect.
+func NewMigrator(database *gorm.DB) (*migrate.Migrator, error) {
+	sqlDB, err := database.DB()
 	if err != nil {
-		log.Fatal("Error migrating model:", err)
+		return nil, err
 	}
 
-	DB = database
+	return migrate.New(sqlDB, database.Dialector.Name())
 }
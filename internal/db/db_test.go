@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-todo-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Password = "it's secret"
+
+	dsn, err := PostgresDSN(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, `host=localhost port=5432 user=postgres password='it\'s secret' dbname=tasks_db sslmode=disable`, dsn)
+}
+
+func TestPostgresDSNFromURL(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.SSLMode = "require"
+
+	cfg.URL = "postgres://app:pw@db.internal:6543/tasks"
+	dsn, err := PostgresDSN(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://app:pw@db.internal:6543/tasks?sslmode=require", dsn)
+
+	cfg.URL = "postgresql://db.internal/tasks?sslmode=verify-full"
+	dsn, err = PostgresDSN(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "postgresql://db.internal/tasks?sslmode=verify-full", dsn)
+
+	cfg.URL = "mysql://db.internal/tasks"
+	_, err = PostgresDSN(cfg)
+	assert.Error(t, err)
+}
+
+func TestConnectSQLiteFile(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = filepath.Join(t.TempDir(), "tasks.db")
+	cfg.BusyTimeout = 3 * time.Second
+
+	database, err := ConnectDatabase(cfg)
+	assert.NoError(t, err)
+
+	var journalMode string
+	var foreignKeys, busyTimeout int
+	database.Raw("PRAGMA journal_mode").Scan(&journalMode)
+	database.Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+	database.Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+
+	assert.Equal(t, "wal", journalMode)
+	assert.Equal(t, 1, foreignKeys)
+	assert.Equal(t, 3000, busyTimeout)
+
+	sqlDB, _ := database.DB()
+	assert.NoError(t, sqlDB.Close())
+}
+
+func TestConnectUnknownDriver(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = "memory"
+
+	_, err := ConnectDatabase(cfg)
+	assert.ErrorContains(t, err, "unsupported database driver")
+}
+
+func TestConnectAppliesPoolSettings(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = filepath.Join(t.TempDir(), "tasks.db")
+	cfg.MaxOpenConns = 7
+
+	database, err := ConnectDatabase(cfg)
+	assert.NoError(t, err)
+
+	sqlDB, _ := database.DB()
+	defer sqlDB.Close()
+	assert.Equal(t, 7, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestConnectWithRetryGivesUp(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = filepath.Join(t.TempDir(), "missing", "tasks.db")
+	cfg.ConnectTimeout = 600 * time.Millisecond
+
+	start := time.Now()
+	_, err := ConnectWithRetry(context.Background(), cfg)
+
+	assert.ErrorContains(t, err, "giving up after 2 attempts")
+	assert.Less(t, time.Since(start), cfg.ConnectTimeout)
+}
+
+func TestConnectWithRetryStopsOnCancel(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = filepath.Join(t.TempDir(), "missing", "tasks.db")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ConnectWithRetry(ctx, cfg)
+	assert.ErrorIs(t, err, context.Canceled)
+}
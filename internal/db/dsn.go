@@ -0,0 +1,77 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go-todo-api/internal/config"
+)
+
+// PostgresDSN builds the connection string for cfg. A connection URL is
+// used as given, with cfg.SSLMode added unless the URL sets its own.
+func PostgresDSN(cfg config.DatabaseConfig) (string, error) {
+	if cfg.URL != "" {
+		u, err := url.Parse(cfg.URL)
+		if err != nil {
+			return "", fmt.Errorf("parsing database URL: %w", err)
+		}
+		if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			return "", fmt.Errorf("database URL must use the postgres scheme, got %q", u.Scheme)
+		}
+
+		query := u.Query()
+		if query.Get("sslmode") == "" && cfg.SSLMode != "" {
+			query.Set("sslmode", cfg.SSLMode)
+			u.RawQuery = query.Encode()
+		}
+		return u.String(), nil
+	}
+
+	pairs := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+	}
+
+	parts := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair.value == "" {
+			continue
+		}
+		parts = append(parts, pair.key+"="+quoteDSNValue(pair.value))
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// quoteDSNValue quotes a keyword/value connection string value when it
+// contains spaces, quotes or backslashes.
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// IsMemorySQLite reports whether path names a private in-memory database.
+func IsMemorySQLite(path string) bool {
+	return path == ":memory:"
+}
+
+// SQLiteDSN builds the connection string for a SQLite database with foreign
+// keys enforced, a busy timeout and, for files, write-ahead logging so
+// readers don't block the writer.
+func SQLiteDSN(cfg config.DatabaseConfig) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_busy_timeout", fmt.Sprint(cfg.BusyTimeout.Milliseconds()))
+	if !IsMemorySQLite(cfg.Path) {
+		params.Set("_journal_mode", "WAL")
+	}
+
+	return cfg.Path + "?" + params.Encode()
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-todo-api/internal/blob"
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the room left in an upload's body for the multipart
+// framing around the file.
+const multipartOverhead = 1 << 20
+
+type AttachmentHandler struct {
+	tasks       *TaskHandler
+	attachments store.AttachmentStore
+	blobs       blob.Store
+	cfg         config.AttachmentConfig
+}
+
+func NewAttachmentHandler(stores store.Stores, blobs blob.Store, cfg config.AttachmentConfig) *AttachmentHandler {
+	return &AttachmentHandler{
+		tasks:       NewTaskHandler(stores),
+		attachments: stores.Attachments,
+		blobs:       blobs,
+		cfg:         cfg,
+	}
+}
+
+// newBlobKey returns a fresh key for a file attached to task. The filename
+// is kept out of it, so keys are safe on any storage.
+func newBlobKey(taskID uint) string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return fmt.Sprintf("tasks/%d/%s", taskID, hex.EncodeToString(raw))
+}
+
+// cleanFilename reduces a client-supplied filename to its last element.
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// allowedType reports whether the detected type is one of the configured
+// ones. Only exact matches count: text/plain does not admit HTML, which
+// mimetype detects as a kind of text.
+func (h *AttachmentHandler) allowedType(detected *mimetype.MIME) bool {
+	for _, allowed := range h.cfg.AllowedTypes {
+		if detected.Is(allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// findAttachment loads the attachment named by the :attachment_id parameter
+// on the task named by :id. It writes the error response and returns false
+// if there is none.
+func (h *AttachmentHandler) findAttachment(c *gin.Context, userID uint) (models.Attachment, bool) {
+	task, ok := h.tasks.findTask(c, userID)
+	if !ok {
+		return models.Attachment{}, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("attachment_id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return models.Attachment{}, false
+	}
+
+	attachment, err := h.attachments.Get(c.Request.Context(), task.ID, uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return attachment, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching attachment"})
+		return attachment, false
+	}
+
+	return attachment, true
+}
+
+// GetAttachments lists a task's attachments, oldest first.
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.tasks.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachments.List(c.Request.Context(), task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching attachments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// CreateAttachment stores the file sent in the "file" field of a multipart
+// form. Its type is detected from the contents; the type the client claims
+// is ignored.
+func (h *AttachmentHandler) CreateAttachment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.tasks.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	tooLarge := fmt.Sprintf("File must be at most %d bytes", h.cfg.MaxSize)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(h.cfg.MaxSize)+multipartOverhead)
+	header, err := c.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file field"})
+		return
+	}
+	if header.Size > int64(h.cfg.MaxSize) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": tooLarge})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading file"})
+		return
+	}
+	defer file.Close()
+
+	detected, err := mimetype.DetectReader(file)
+	if err == nil {
+		_, err = file.Seek(0, 0)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error reading file"})
+		return
+	}
+	if !h.allowedType(detected) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": fmt.Sprintf("File type %s is not allowed", detected.String()),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	attachment := models.Attachment{
+		TaskID:      task.ID,
+		UploaderID:  userID.(uint),
+		Filename:    cleanFilename(header.Filename),
+		ContentType: detected.String(),
+		Size:        header.Size,
+		Key:         newBlobKey(task.ID),
+	}
+	if err := h.blobs.Put(ctx, attachment.Key, file, attachment.Size, attachment.ContentType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing file"})
+		return
+	}
+
+	if err := h.attachments.Create(ctx, &attachment); err != nil {
+		if err := h.blobs.Delete(ctx, attachment.Key); err != nil {
+			log.Printf("Error deleting attachment blob %s: %v", attachment.Key, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating attachment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadAttachment streams an attachment's contents. It is always served
+// as a download, so an uploaded file cannot render in the API's origin.
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	attachment, ok := h.findAttachment(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	r, err := h.blobs.Get(c.Request.Context(), attachment.Key)
+	if errors.Is(err, blob.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment content not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading file"})
+		return
+	}
+	defer r.Close()
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, r, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// DeleteAttachment removes an attachment. Its contents are deleted from
+// storage by the sweeper.
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	attachment, ok := h.findAttachment(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.attachments.Delete(c.Request.Context(), &attachment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
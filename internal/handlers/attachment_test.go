@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-todo-api/internal/blob"
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader is enough of a PNG file for its type to be detected.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// setupAttachmentRouter serves the task and attachment routes for user 1,
+// with blobs stored in a temporary directory and a 1 KiB size limit.
+func setupAttachmentRouter(t *testing.T) (*gin.Engine, store.Stores, blob.Store) {
+	stores := store.NewGormStores(testutils.SetupTestDB(t), 0)
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create blob store: %v", err)
+	}
+
+	cfg := config.Default().Attachments
+	cfg.MaxSize = 1024
+	h := handlers.NewTaskHandler(stores)
+	ah := handlers.NewAttachmentHandler(stores, blobs, cfg)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.POST("/tasks", h.CreateTask)
+	r.GET("/tasks/:id/attachments", ah.GetAttachments)
+	r.POST("/tasks/:id/attachments", ah.CreateAttachment)
+	r.GET("/tasks/:id/attachments/:attachment_id", ah.DownloadAttachment)
+	r.DELETE("/tasks/:id/attachments/:attachment_id", ah.DeleteAttachment)
+
+	return r, stores, blobs
+}
+
+// upload posts content as the named form field of a multipart request.
+func upload(r *gin.Engine, path, field, filename string, content []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile(field, filename)
+	part.Write(content)
+	form.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAttachments(t *testing.T) {
+	r, stores, blobs := setupAttachmentRouter(t)
+	ctx := context.Background()
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Fix login page"}`)
+
+	w := upload(r, "/tasks/1/attachments", "file", `C:\shots\login.png`, pngHeader)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var attachment models.Attachment
+	json.Unmarshal(w.Body.Bytes(), &attachment)
+	assert.Equal(t, uint(1), attachment.TaskID)
+	assert.Equal(t, uint(1), attachment.UploaderID)
+	assert.Equal(t, "login.png", attachment.Filename)
+	assert.Equal(t, "image/png", attachment.ContentType)
+	assert.Equal(t, int64(len(pngHeader)), attachment.Size)
+	assert.NotContains(t, w.Body.String(), "tasks/1/")
+
+	w = upload(r, "/tasks/1/attachments", "file", "notes.txt", []byte("plain notes"))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = serve(r, http.MethodGet, "/tasks/1/attachments", "")
+	var attachments []models.Attachment
+	json.Unmarshal(w.Body.Bytes(), &attachments)
+	if assert.Len(t, attachments, 2) {
+		assert.Equal(t, "login.png", attachments[0].Filename)
+		assert.Equal(t, "text/plain; charset=utf-8", attachments[1].ContentType)
+	}
+
+	w = serve(r, http.MethodGet, "/tasks/1/attachments/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, pngHeader, w.Body.Bytes())
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=login.png`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/1/attachments/9", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/2/attachments", "").Code)
+
+	// Deleting queues the blob for the sweeper.
+	w = serve(r, http.MethodDelete, "/tasks/1/attachments/1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/1/attachments/1", "").Code)
+
+	blob.Sweep(ctx, stores.Attachments, blobs)
+	keys, _ := stores.Attachments.OrphanedBlobs(ctx, 10)
+	assert.Empty(t, keys)
+}
+
+func TestCreateAttachmentLimits(t *testing.T) {
+	r, _, _ := setupAttachmentRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Fix login page"}`)
+
+	w := upload(r, "/tasks/1/attachments", "file", "page.html", []byte("<html><body>hi</body></html>"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "text/html")
+
+	w = upload(r, "/tasks/1/attachments", "file", "big.txt", []byte(strings.Repeat("a", 1025)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = upload(r, "/tasks/1/attachments", "file", "huge.txt", []byte(strings.Repeat("a", 2<<20)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = upload(r, "/tasks/1/attachments", "other", "notes.txt", []byte("notes"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = upload(r, "/tasks/9/attachments", "file", "notes.txt", []byte("notes"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPurgedTaskAttachmentsAreSwept(t *testing.T) {
+	r, stores, blobs := setupAttachmentRouter(t)
+	ctx := context.Background()
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Fix login page"}`)
+	upload(r, "/tasks/1/attachments", "file", "login.png", pngHeader)
+
+	attachments, _ := stores.Attachments.List(ctx, 1)
+	key := attachments[0].Key
+
+	task, _ := stores.Tasks.Get(ctx, 1, 1)
+	assert.NoError(t, stores.Tasks.Delete(ctx, &task, true))
+	assert.NoError(t, stores.Tasks.Purge(ctx, &task))
+
+	content, err := blobs.Get(ctx, key)
+	if assert.NoError(t, err) {
+		content.Close()
+	}
+
+	blob.Sweep(ctx, stores.Attachments, blobs)
+	_, err = blobs.Get(ctx, key)
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
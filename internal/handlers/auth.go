@@ -1,18 +1,32 @@
 package handlers
 
 import (
-	"go-todo-api/internal/db"
+	"context"
+	"errors"
 	"go-todo-api/internal/models"
 	"net/http"
 	"time"
 
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/store"
 	"go-todo-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 )
 
-func Signup(c *gin.Context) {
+// AuthHandler serves the signup, login and token endpoints.
+type AuthHandler struct {
+	cfg           config.AuthConfig
+	tokens        *utils.TokenManager
+	users         store.UserStore
+	refreshTokens store.RefreshTokenStore
+}
+
+func NewAuthHandler(cfg config.AuthConfig, tokens *utils.TokenManager, users store.UserStore, refreshTokens store.RefreshTokenStore) *AuthHandler {
+	return &AuthHandler{cfg: cfg, tokens: tokens, users: users, refreshTokens: refreshTokens}
+}
+
+func (h *AuthHandler) Signup(c *gin.Context) {
 	var input struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -23,7 +37,7 @@ func Signup(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(input.Password)
+	hashedPassword, err := utils.HashPassword(input.Password, h.cfg.BcryptCost)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating password hash"})
 		return
@@ -34,7 +48,7 @@ func Signup(c *gin.Context) {
 		PasswordHash: string(hashedPassword),
 	}
 
-	if err := db.DB.Create(&user).Error; err != nil {
+	if err := h.users.Create(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Error creating user (possibly duplicate email)"})
 		return
 	}
@@ -42,7 +56,7 @@ func Signup(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
 }
 
-func Login(c *gin.Context) {
+func (h *AuthHandler) Login(c *gin.Context) {
 	var input struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
@@ -53,8 +67,8 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	var user models.User
-	if err := db.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+	user, err := h.users.GetByEmail(c.Request.Context(), input.Email)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
@@ -64,73 +78,155 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, err := utils.GenerateAccessToken(user.ID)
+	accessToken, err := h.tokens.GenerateAccessToken(user.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating token"})
 		return
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user.ID)
+	familyID, err := utils.NewTokenID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating refresh token"})
+		return
+	}
+
+	refreshToken, err := h.issueRefreshToken(c.Request.Context(), user.ID, familyID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating refresh token"})
+		return
+	}
+
+	h.setRefreshCookie(c, refreshToken, int(h.tokens.RefreshTokenTTL.Seconds()))
+
+	c.JSON(http.StatusOK, gin.H{"token": accessToken})
+}
+
+// issueRefreshToken creates a refresh token in the given family and stores
+// its hash so it can later be rotated or revoked.
+func (h *AuthHandler) issueRefreshToken(ctx context.Context, userID uint, familyID string) (string, error) {
+	refreshToken, err := h.tokens.GenerateRefreshToken(userID)
+	if err != nil {
+		return "", err
+	}
+
+	record := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(h.tokens.RefreshTokenTTL),
+	}
+	if err := h.refreshTokens.Create(ctx, &record); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// setRefreshCookie stores the refresh token in an HTTP-only cookie. An
+// empty token with a negative maxAge deletes the cookie.
+func (h *AuthHandler) setRefreshCookie(c *gin.Context, refreshToken string, maxAge int) {
+	switch h.cfg.Cookie.SameSite {
+	case "strict":
+		c.SetSameSite(http.SameSiteStrictMode)
+	case "none":
+		c.SetSameSite(http.SameSiteNoneMode)
+	default:
+		c.SetSameSite(http.SameSiteLaxMode)
 	}
 
 	c.SetCookie(
 		"refresh_token",
 		refreshToken,
-		7*24*60*60,
-		"/",
-		"",
-		false,
+		maxAge,
+		h.cfg.Cookie.Path,
+		h.cfg.Cookie.Domain,
+		h.cfg.Cookie.Secure,
 		true,
 	)
-
-	c.JSON(http.StatusOK, gin.H{"token": accessToken})
 }
 
-func RefreshToken(c *gin.Context) {
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	refreshToken, err := c.Cookie("refresh_token")
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token missing"})
 		return
 	}
 
-	token, err := jwt.ParseWithClaims(refreshToken, &utils.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return utils.JwtKey, nil
-	})
+	claims, err := h.tokens.ParseToken(refreshToken, utils.TokenTypeRefresh)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
 
-	if err != nil || !token.Valid {
+	stored, err := h.refreshTokens.GetByHash(c.Request.Context(), utils.HashToken(refreshToken))
+	if errors.Is(err, store.ErrNotFound) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error rotating refresh token"})
+		return
+	}
 
-	claims, ok := token.Claims.(*utils.Claims)
-	if !ok || claims.ExpiresAt.Time.Before(time.Now()) {
+	if stored.ExpiresAt.Before(time.Now()) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token expired"})
 		return
 	}
 
-	newAccessToken, err := utils.GenerateAccessToken(claims.UserID)
+	// Marking the token as used only succeeds once, so a second presentation
+	// of the same token means it leaked and the whole family is revoked.
+	active, err := h.refreshTokens.MarkUsed(c.Request.Context(), stored.ID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error rotating refresh token"})
+		return
+	}
+
+	if !active {
+		if err := h.refreshTokens.RevokeFamily(c.Request.Context(), stored.FamilyID, time.Now()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking refresh token"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected"})
+		return
+	}
+
+	newRefreshToken, err := h.issueRefreshToken(c.Request.Context(), stored.UserID, stored.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating refresh token"})
+		return
+	}
+
+	newAccessToken, err := h.tokens.GenerateAccessToken(claims.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating new access token"})
 		return
 	}
 
+	h.setRefreshCookie(c, newRefreshToken, int(h.tokens.RefreshTokenTTL.Seconds()))
+
 	c.JSON(http.StatusOK, gin.H{
 		"access_token": newAccessToken,
 	})
 }
 
-func Logout(c *gin.Context) {
-	c.SetCookie(
-		"refresh_token",
-		"",
-		-1,
-		"/",
-		"",
-		false,
-		true,
-	)
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if refreshToken, err := c.Cookie("refresh_token"); err == nil && refreshToken != "" {
+		stored, err := h.refreshTokens.GetByHash(c.Request.Context(), utils.HashToken(refreshToken))
+		if err == nil {
+			if err := h.refreshTokens.RevokeFamily(c.Request.Context(), stored.FamilyID, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking refresh token"})
+				return
+			}
+		}
+	}
+
+	h.setRefreshCookie(c, "", -1)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
 }
+
+// JWKS publishes the public signing keys so other services can verify
+// access tokens offline.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.tokens.Keys.JWKS())
+}
@@ -1,9 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
-	"go-todo-api/internal/db"
 	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
 	"go-todo-api/internal/testutils"
 	"go-todo-api/internal/utils"
 	"net/http"
@@ -13,13 +14,19 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
+func newTestAuthHandler(t *testing.T, stores store.Stores) *AuthHandler {
+	return NewAuthHandler(testutils.TestConfig().Auth, testutils.SetupTokenManager(t), stores.Users, stores.RefreshTokens)
+}
+
 func TestSignupSuccess(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
 
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
 	r := gin.Default()
-	r.POST("/signup", Signup)
+	r.POST("/signup", h.Signup)
 
 	body := `{"email":"teste@example.com","password":"123456"}`
 	req, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
@@ -36,10 +43,11 @@ func TestSignupSuccess(t *testing.T) {
 }
 
 func TestSignupInvalidJSON(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
 
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
 	r := gin.Default()
-	r.POST("/signup", Signup)
+	r.POST("/signup", h.Signup)
 
 	body := `{"email":1, "password":true}`
 	req, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
@@ -53,12 +61,13 @@ func TestSignupInvalidJSON(t *testing.T) {
 }
 
 func TestSignupDuplicateEmail(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
 
-	db.DB.Create(&models.User{Email: "teste@example.com", PasswordHash: "senha"})
+	database.Create(&models.User{Email: "teste@example.com", PasswordHash: "senha"})
 
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
 	r := gin.Default()
-	r.POST("/signup", Signup)
+	r.POST("/signup", h.Signup)
 
 	body := `{"email":"teste@example.com","password":"nova"}`
 	req, _ := http.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
@@ -72,13 +81,14 @@ func TestSignupDuplicateEmail(t *testing.T) {
 }
 
 func TestLoginSuccess(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
 
-	hashed, _ := utils.HashPassword("123456")
-	db.DB.Create(&models.User{Email: "teste@example.com", PasswordHash: hashed})
+	hashed, _ := utils.HashPassword("123456", bcrypt.MinCost)
+	database.Create(&models.User{Email: "teste@example.com", PasswordHash: hashed})
 
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
 	r := gin.Default()
-	r.POST("/login", Login)
+	r.POST("/login", h.Login)
 
 	body := `{"email":"teste@example.com","password":"123456"}`
 	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
@@ -94,10 +104,11 @@ func TestLoginSuccess(t *testing.T) {
 }
 
 func TestLoginInvalidJSON(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
 
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
 	r := gin.Default()
-	r.POST("/login", Login)
+	r.POST("/login", h.Login)
 
 	body := `{"email":123,"password":false}`
 	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
@@ -110,10 +121,11 @@ func TestLoginInvalidJSON(t *testing.T) {
 }
 
 func TestLoginUserNotFound(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
 
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
 	r := gin.Default()
-	r.POST("/login", Login)
+	r.POST("/login", h.Login)
 
 	body := `{"email":"naoexiste@example.com","password":"123"}`
 	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
@@ -127,13 +139,14 @@ func TestLoginUserNotFound(t *testing.T) {
 }
 
 func TestLoginWrongPassword(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
 
-	hashed, _ := utils.HashPassword("correta")
-	db.DB.Create(&models.User{Email: "teste@example.com", PasswordHash: hashed})
+	hashed, _ := utils.HashPassword("correta", bcrypt.MinCost)
+	database.Create(&models.User{Email: "teste@example.com", PasswordHash: hashed})
 
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
 	r := gin.Default()
-	r.POST("/login", Login)
+	r.POST("/login", h.Login)
 
 	body := `{"email":"teste@example.com","password":"errada"}`
 	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
@@ -145,28 +158,116 @@ func TestLoginWrongPassword(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 }
 
-func TestRefreshTokenSuccess(t *testing.T) {
-	token, _ := utils.GenerateRefreshToken(1)
+// loginForRefreshCookie logs a fresh user in and returns the refresh cookie.
+func loginForRefreshCookie(t *testing.T, h *AuthHandler, r *gin.Engine) *http.Cookie {
+	hashed, _ := utils.HashPassword("123456", bcrypt.MinCost)
+	h.users.Create(context.Background(), &models.User{Email: "teste@example.com", PasswordHash: hashed})
 
-	r := gin.Default()
-	r.GET("/refresh", RefreshToken)
+	body := `{"email":"teste@example.com","password":"123456"}`
+	req, _ := http.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
 
-	req, _ := http.NewRequest(http.MethodGet, "/refresh", nil)
-	req.AddCookie(&http.Cookie{
-		Name:  "refresh_token",
-		Value: token,
-	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	return refreshCookie(t, w)
+}
 
+func refreshCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "refresh_token" {
+			return cookie
+		}
+	}
+	t.Fatalf("refresh_token cookie not set")
+	return nil
+}
+
+func refreshWith(r *gin.Engine, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRefreshTokenSuccess(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
+	r := gin.Default()
+	r.POST("/login", h.Login)
+	r.POST("/refresh", h.RefreshToken)
+
+	cookie := loginForRefreshCookie(t, h, r)
+
+	w := refreshWith(r, cookie)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "access_token")
+	assert.NotEqual(t, cookie.Value, refreshCookie(t, w).Value)
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
+	r := gin.Default()
+	r.POST("/login", h.Login)
+	r.POST("/refresh", h.RefreshToken)
+
+	original := loginForRefreshCookie(t, h, r)
+
+	w := refreshWith(r, original)
+	assert.Equal(t, http.StatusOK, w.Code)
+	rotated := refreshCookie(t, w)
+
+	w = refreshWith(r, original)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Refresh token reuse detected")
+
+	w = refreshWith(r, rotated)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var active int64
+	database.Model(&models.RefreshToken{}).Where("revoked_at IS NULL").Count(&active)
+	assert.Equal(t, int64(0), active)
+}
+
+func TestRefreshTokenNotStored(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
+	token, _ := h.tokens.GenerateRefreshToken(1)
+
+	r := gin.Default()
+	r.POST("/refresh", h.RefreshToken)
+
+	w := refreshWith(r, &http.Cookie{Name: "refresh_token", Value: token})
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid refresh token")
+}
+
+func TestRefreshTokenRejectsAccessToken(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
+	token, _ := h.tokens.GenerateAccessToken(1)
+
+	r := gin.Default()
+	r.POST("/refresh", h.RefreshToken)
+
+	w := refreshWith(r, &http.Cookie{Name: "refresh_token", Value: token})
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid refresh token")
 }
 
 func TestRefreshTokenMissingCookie(t *testing.T) {
+	h := newTestAuthHandler(t, store.NewMemoryStores())
 	r := gin.Default()
-	r.GET("/refresh", RefreshToken)
+	r.GET("/refresh", h.RefreshToken)
 
 	req, _ := http.NewRequest(http.MethodGet, "/refresh", nil)
 	w := httptest.NewRecorder()
@@ -177,8 +278,9 @@ func TestRefreshTokenMissingCookie(t *testing.T) {
 }
 
 func TestRefreshTokenInvalidToken(t *testing.T) {
+	h := newTestAuthHandler(t, store.NewMemoryStores())
 	r := gin.Default()
-	r.GET("/refresh", RefreshToken)
+	r.GET("/refresh", h.RefreshToken)
 
 	req, _ := http.NewRequest(http.MethodGet, "/refresh", nil)
 	req.AddCookie(&http.Cookie{
@@ -194,8 +296,9 @@ func TestRefreshTokenInvalidToken(t *testing.T) {
 }
 
 func TestLogoutSuccess(t *testing.T) {
+	h := newTestAuthHandler(t, store.NewMemoryStores())
 	r := gin.Default()
-	r.POST("/logout", Logout)
+	r.POST("/logout", h.Logout)
 
 	req, _ := http.NewRequest(http.MethodPost, "/logout", nil)
 	w := httptest.NewRecorder()
@@ -203,4 +306,39 @@ func TestLogoutSuccess(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Logout successful")
-}
\ No newline at end of file
+}
+
+func TestLogoutRevokesRefreshToken(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+
+	h := newTestAuthHandler(t, store.NewGormStores(database, 0))
+	r := gin.Default()
+	r.POST("/login", h.Login)
+	r.POST("/refresh", h.RefreshToken)
+	r.POST("/logout", h.Logout)
+
+	cookie := loginForRefreshCookie(t, h, r)
+
+	req, _ := http.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = refreshWith(r, cookie)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestJWKSHidesSymmetricKeys(t *testing.T) {
+	h := newTestAuthHandler(t, store.NewMemoryStores())
+	r := gin.Default()
+	r.GET("/.well-known/jwks.json", h.JWKS)
+
+	req, _ := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"keys":[]}`, w.Body.String())
+}
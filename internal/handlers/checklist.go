@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxChecklistTextLength = 500
+
+// checklistItemInput is the body of the checklist item endpoints. Fields
+// left out of an update keep their value.
+type checklistItemInput struct {
+	Text    *string `json:"text"`
+	Checked *bool   `json:"checked"`
+}
+
+type checklistOrderInput struct {
+	IDs []uint `json:"ids"`
+}
+
+// normalizeChecklistText trims an item's text and checks its length.
+func normalizeChecklistText(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		return "", errors.New("text must not be empty")
+	case utf8.RuneCountInString(text) > maxChecklistTextLength:
+		return "", fmt.Errorf("text must be at most %d characters", maxChecklistTextLength)
+	}
+	return text, nil
+}
+
+// findChecklistItem loads the item named by the :item_id parameter on the
+// task named by :id. It writes the error response and returns false if
+// there is none.
+func (h *TaskHandler) findChecklistItem(c *gin.Context, userID uint) (models.ChecklistItem, bool) {
+	task, ok := h.findTask(c, userID)
+	if !ok {
+		return models.ChecklistItem{}, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("item_id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return models.ChecklistItem{}, false
+	}
+
+	item, err := h.checklists.Get(c.Request.Context(), task.ID, uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Checklist item not found"})
+		return item, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching checklist item"})
+		return item, false
+	}
+
+	return item, true
+}
+
+// GetChecklist lists a task's checklist items in order.
+func (h *TaskHandler) GetChecklist(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	items, err := h.checklists.List(c.Request.Context(), task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching checklist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// CreateChecklistItem appends an item to a task's checklist.
+func (h *TaskHandler) CreateChecklistItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input checklistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Text == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "text is required"})
+		return
+	}
+
+	text, err := normalizeChecklistText(*input.Text)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	item := models.ChecklistItem{TaskID: task.ID, Text: text}
+	if input.Checked != nil {
+		item.Checked = *input.Checked
+	}
+	if err := h.checklists.Create(changeContext(c), &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating checklist item"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, item)
+}
+
+// UpdateChecklistItem changes the text of an item or checks it.
+func (h *TaskHandler) UpdateChecklistItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input checklistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, ok := h.findChecklistItem(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if input.Text != nil {
+		text, err := normalizeChecklistText(*input.Text)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		item.Text = text
+	}
+	if input.Checked != nil {
+		item.Checked = *input.Checked
+	}
+
+	if err := h.checklists.Update(changeContext(c), &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating checklist item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *TaskHandler) DeleteChecklistItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	item, ok := h.findChecklistItem(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.checklists.Delete(changeContext(c), &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting checklist item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Checklist item deleted"})
+}
+
+// ReorderChecklist puts a task's checklist in the order of the given item
+// ids, which must list every item once, and returns the reordered list.
+func (h *TaskHandler) ReorderChecklist(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input checklistOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	err := h.checklists.Reorder(changeContext(c), task.ID, input.IDs)
+	if errors.Is(err, store.ErrInvalidOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must list every item of the checklist once"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reordering checklist"})
+		return
+	}
+
+	items, err := h.checklists.List(c.Request.Context(), task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching checklist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupChecklistRouter serves the task and checklist routes for user 1.
+func setupChecklistRouter(t *testing.T) *gin.Engine {
+	stores := store.NewGormStores(testutils.SetupTestDB(t), 0)
+	h := handlers.NewTaskHandler(stores)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.POST("/tasks", h.CreateTask)
+	r.GET("/tasks/:id", h.GetTask)
+	r.PATCH("/tasks/:id", h.PatchTask)
+	r.GET("/tasks/:id/checklist", h.GetChecklist)
+	r.POST("/tasks/:id/checklist", h.CreateChecklistItem)
+	r.PUT("/tasks/:id/checklist/order", h.ReorderChecklist)
+	r.PATCH("/tasks/:id/checklist/:item_id", h.UpdateChecklistItem)
+	r.DELETE("/tasks/:id/checklist/:item_id", h.DeleteChecklistItem)
+
+	return r
+}
+
+func getChecklistTask(t *testing.T, r *gin.Engine) models.Task {
+	var task models.Task
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks/1", "").Body.Bytes(), &task)
+	return task
+}
+
+func TestChecklist(t *testing.T) {
+	r := setupChecklistRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Pack for trip"}`)
+
+	w := serve(r, http.MethodPost, "/tasks/1/checklist", `{"text":"  Passport "}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var item models.ChecklistItem
+	json.Unmarshal(w.Body.Bytes(), &item)
+	assert.Equal(t, uint(1), item.TaskID)
+	assert.Equal(t, "Passport", item.Text)
+	assert.False(t, item.Checked)
+	assert.Equal(t, 1, item.Position)
+
+	serve(r, http.MethodPost, "/tasks/1/checklist", `{"text":"Charger","checked":true}`)
+	serve(r, http.MethodPost, "/tasks/1/checklist", `{"text":"Tickets"}`)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/checklist", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/checklist", `{"text":" "}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/checklist", `{"text":"`+strings.Repeat("a", 501)+`"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPost, "/tasks/9/checklist", `{"text":"x"}`).Code)
+
+	task := getChecklistTask(t, r)
+	assert.Equal(t, models.ChecklistProgress{Checked: 1, Total: 3}, task.Checklist)
+
+	w = serve(r, http.MethodPatch, "/tasks/1/checklist/1", `{"checked":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &item)
+	assert.Equal(t, "Passport", item.Text)
+	assert.True(t, item.Checked)
+
+	w = serve(r, http.MethodPatch, "/tasks/1/checklist/3", `{"text":"Train tickets"}`)
+	json.Unmarshal(w.Body.Bytes(), &item)
+	assert.Equal(t, "Train tickets", item.Text)
+	assert.False(t, item.Checked)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPatch, "/tasks/1/checklist/9", `{"checked":true}`).Code)
+
+	w = serve(r, http.MethodPut, "/tasks/1/checklist/order", `{"ids":[3,1,2]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var items []models.ChecklistItem
+	json.Unmarshal(w.Body.Bytes(), &items)
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, "Train tickets", items[0].Text)
+		assert.Equal(t, "Passport", items[1].Text)
+		assert.Equal(t, 2, items[1].Position)
+	}
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPut, "/tasks/1/checklist/order", `{"ids":[3,1]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPut, "/tasks/1/checklist/order", `{"ids":[3,1,9]}`).Code)
+
+	w = serve(r, http.MethodDelete, "/tasks/1/checklist/2", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(r, http.MethodGet, "/tasks/1/checklist", "")
+	json.Unmarshal(w.Body.Bytes(), &items)
+	assert.Len(t, items, 2)
+
+	task = getChecklistTask(t, r)
+	assert.Equal(t, models.ChecklistProgress{Checked: 1, Total: 2}, task.Checklist)
+	assert.False(t, task.Done)
+}
+
+func TestChecklistAutoCompletesTask(t *testing.T) {
+	r := setupChecklistRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Pack for trip","checklist_auto_complete":true}`)
+	serve(r, http.MethodPost, "/tasks/1/checklist", `{"text":"Passport"}`)
+	serve(r, http.MethodPost, "/tasks/1/checklist", `{"text":"Charger"}`)
+
+	serve(r, http.MethodPatch, "/tasks/1/checklist/1", `{"checked":true}`)
+	assert.False(t, getChecklistTask(t, r).Done)
+
+	serve(r, http.MethodPatch, "/tasks/1/checklist/2", `{"checked":true}`)
+	task := getChecklistTask(t, r)
+	assert.True(t, task.Done)
+	assert.True(t, task.ChecklistAutoComplete)
+
+	serve(r, http.MethodPatch, "/tasks/1/checklist/2", `{"checked":false}`)
+	assert.False(t, getChecklistTask(t, r).Done)
+
+	// Without the flag, checking every item leaves the task alone.
+	w := serve(r, http.MethodPatch, "/tasks/1", `{"checklist_auto_complete":false}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	serve(r, http.MethodPatch, "/tasks/1/checklist/2", `{"checked":true}`)
+	assert.False(t, getChecklistTask(t, r).Done)
+
+	// Recurring tasks are completed by hand.
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"checklist_auto_complete":true,"rrule":"FREQ=WEEKLY","due_at":"2025-01-06T09:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(r, http.MethodPost, "/tasks", `{"title":"Chores","checklist_auto_complete":true,"rrule":"FREQ=WEEKLY","due_at":"2025-01-06T09:00:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultCommentLimit = 50
+	maxCommentLimit     = 100
+	maxCommentLength    = 10000
+)
+
+type commentInput struct {
+	Body string `json:"body"`
+}
+
+type commentPage struct {
+	Data   []models.Comment `json:"data"`
+	Total  int64            `json:"total"`
+	Limit  int              `json:"limit"`
+	Offset int              `json:"offset"`
+}
+
+// validateCommentBody checks a comment's Markdown body, which is otherwise
+// stored as sent.
+func validateCommentBody(body string) error {
+	switch {
+	case strings.TrimSpace(body) == "":
+		return errors.New("body must not be empty")
+	case utf8.RuneCountInString(body) > maxCommentLength:
+		return fmt.Errorf("body must be at most %d characters", maxCommentLength)
+	}
+	return nil
+}
+
+// parseCommentPage reads the limit and offset of a comment listing.
+func parseCommentPage(c *gin.Context) (limit, offset int, err error) {
+	limit = defaultCommentLimit
+
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxCommentLimit {
+			return 0, 0, fmt.Errorf("Invalid limit parameter: must be between 1 and %d", maxCommentLimit)
+		}
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("Invalid offset parameter: must be a non-negative integer")
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// findComment loads the comment named by the :comment_id parameter on task.
+// It writes the error response and returns false if there is none.
+func (h *TaskHandler) findComment(c *gin.Context, task models.Task) (models.Comment, bool) {
+	id, err := strconv.ParseUint(c.Param("comment_id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return models.Comment{}, false
+	}
+
+	comment, err := h.comments.Get(c.Request.Context(), task.ID, uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return comment, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching comment"})
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// findOwnComment is findComment for comments the user may change, which
+// are the ones they wrote.
+func (h *TaskHandler) findOwnComment(c *gin.Context, userID uint) (models.Comment, bool) {
+	task, ok := h.findTask(c, userID)
+	if !ok {
+		return models.Comment{}, false
+	}
+
+	comment, ok := h.findComment(c, task)
+	if !ok {
+		return comment, false
+	}
+
+	if comment.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can change a comment"})
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// GetComments returns a page of a task's comments, oldest first.
+func (h *TaskHandler) GetComments(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	limit, offset, err := parseCommentPage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	list, err := h.comments.List(c.Request.Context(), task.ID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, commentPage{
+		Data:   list.Comments,
+		Total:  list.Total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (h *TaskHandler) CreateComment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input commentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCommentBody(input.Body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	comment := models.Comment{TaskID: task.ID, AuthorID: userID.(uint), Body: input.Body}
+	if err := h.comments.Create(c.Request.Context(), &comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating comment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *TaskHandler) UpdateComment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var input commentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCommentBody(input.Body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, ok := h.findOwnComment(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	comment.Body = input.Body
+	if err := h.comments.Update(c.Request.Context(), &comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *TaskHandler) DeleteComment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	comment, ok := h.findOwnComment(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.comments.Delete(c.Request.Context(), &comment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupCommentRouter serves the task and comment routes for user 1 and
+// returns the stores behind them.
+func setupCommentRouter(t *testing.T) (*gin.Engine, store.Stores) {
+	stores := store.NewGormStores(testutils.SetupTestDB(t), 0)
+	h := handlers.NewTaskHandler(stores)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.GET("/tasks", h.GetTasks)
+	r.POST("/tasks", h.CreateTask)
+	r.GET("/tasks/:id/comments", h.GetComments)
+	r.POST("/tasks/:id/comments", h.CreateComment)
+	r.PUT("/tasks/:id/comments/:comment_id", h.UpdateComment)
+	r.DELETE("/tasks/:id/comments/:comment_id", h.DeleteComment)
+
+	return r, stores
+}
+
+func TestComments(t *testing.T) {
+	r, stores := setupCommentRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Plan launch"}`)
+
+	w := serve(r, http.MethodPost, "/tasks/1/comments", `{"body":"Draft is in **docs/launch.md**"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var comment models.Comment
+	json.Unmarshal(w.Body.Bytes(), &comment)
+	assert.Equal(t, uint(1), comment.TaskID)
+	assert.Equal(t, uint(1), comment.AuthorID)
+	assert.Equal(t, "Draft is in **docs/launch.md**", comment.Body)
+
+	for _, body := range []string{"second", "third"} {
+		serve(r, http.MethodPost, "/tasks/1/comments", `{"body":"`+body+`"}`)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/comments", `{"body":"  "}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/comments", `{"body":"`+strings.Repeat("a", 10001)+`"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPost, "/tasks/9/comments", `{"body":"hi"}`).Code)
+
+	var page struct {
+		Data  []models.Comment `json:"data"`
+		Total int64            `json:"total"`
+	}
+	w = serve(r, http.MethodGet, "/tasks/1/comments?limit=2&offset=1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, int64(3), page.Total)
+	if assert.Len(t, page.Data, 2) {
+		assert.Equal(t, "second", page.Data[0].Body)
+	}
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodGet, "/tasks/1/comments?limit=0", "").Code)
+
+	var tasks struct {
+		Data []models.Task `json:"data"`
+	}
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks", "").Body.Bytes(), &tasks)
+	if assert.Len(t, tasks.Data, 1) {
+		assert.Equal(t, int64(3), tasks.Data[0].CommentCount)
+	}
+
+	w = serve(r, http.MethodPut, "/tasks/1/comments/1", `{"body":"Draft moved to the wiki"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &comment)
+	assert.Equal(t, "Draft moved to the wiki", comment.Body)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPut, "/tasks/1/comments/99", `{"body":"x"}`).Code)
+
+	// Only the author may change a comment.
+	other := models.Comment{TaskID: 1, AuthorID: 2, Body: "not yours"}
+	assert.NoError(t, stores.Comments.Create(t.Context(), &other))
+	path := "/tasks/1/comments/" + itoa(other.ID)
+	assert.Equal(t, http.StatusForbidden, serve(r, http.MethodPut, path, `{"body":"mine now"}`).Code)
+	assert.Equal(t, http.StatusForbidden, serve(r, http.MethodDelete, path, "").Code)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/tasks/1/comments/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodDelete, "/tasks/1/comments/1", "").Code)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"go-todo-api/internal/migrate"
+	"go-todo-api/internal/store"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+// HealthCheck reports whether a dependency is usable.
+type HealthCheck func(ctx context.Context) error
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	checks       map[string]HealthCheck
+	shuttingDown atomic.Bool
+}
+
+// NewHealthHandler returns a handler whose readiness probe runs checks,
+// reported under their map keys.
+func NewHealthHandler(checks map[string]HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks}
+}
+
+// DatabaseChecks returns the readiness checks for a SQL database: that it
+// answers and that no migration is pending.
+func DatabaseChecks(database *gorm.DB, migrator *migrate.Migrator) map[string]HealthCheck {
+	return map[string]HealthCheck{
+		"database": func(ctx context.Context) error {
+			return store.Ping(ctx, database)
+		},
+		"migrations": func(ctx context.Context) error {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("%d pending migrations, next is %d_%s", len(pending), pending[0].Version, pending[0].Name)
+			}
+			return nil
+		},
+	}
+}
+
+// SetShuttingDown makes the readiness probe fail so load balancers stop
+// routing new requests while in-flight ones drain.
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+type healthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func runCheck(check func() error) healthCheck {
+	start := time.Now()
+	err := check()
+	result := healthCheck{
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Liveness reports that the process is up. It never touches dependencies.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness checks every dependency and answers 503 if any of them fails
+// or the server is shutting down.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	checks := map[string]healthCheck{}
+	for name, check := range h.checks {
+		checks[name] = runCheck(func() error { return check(ctx) })
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, code = "error", http.StatusServiceUnavailable
+		}
+	}
+	if h.shuttingDown.Load() {
+		status, code = "shutting_down", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{"status": status, "checks": checks})
+}
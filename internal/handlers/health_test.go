@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"go-todo-api/internal/db"
+	"go-todo-api/internal/migrate"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks"`
+}
+
+func probe(t *testing.T, h *HealthHandler, path string) (int, readinessResponse) {
+	r := gin.Default()
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body readinessResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func databaseChecks(t *testing.T) (map[string]HealthCheck, *migrate.Migrator) {
+	database := testutils.SetupTestDB(t)
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	return DatabaseChecks(database, migrator), migrator
+}
+
+func TestLiveness(t *testing.T) {
+	code, body := probe(t, NewHealthHandler(nil), "/healthz")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body.Status)
+}
+
+func TestReadinessReady(t *testing.T) {
+	checks, _ := databaseChecks(t)
+
+	code, body := probe(t, NewHealthHandler(checks), "/readyz")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body.Status)
+	assert.Equal(t, "ok", body.Checks["database"].Status)
+	assert.Equal(t, "ok", body.Checks["migrations"].Status)
+}
+
+func TestReadinessMissingMigration(t *testing.T) {
+	checks, migrator := databaseChecks(t)
+	migrator.Down(context.Background())
+
+	code, body := probe(t, NewHealthHandler(checks), "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "error", body.Status)
+	assert.Equal(t, "ok", body.Checks["database"].Status)
+	assert.Contains(t, body.Checks["migrations"].Error, "1 pending migrations")
+}
+
+func TestReadinessShuttingDown(t *testing.T) {
+	checks, _ := databaseChecks(t)
+	h := NewHealthHandler(checks)
+	h.SetShuttingDown()
+
+	code, body := probe(t, h, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting_down", body.Status)
+
+	code, _ = probe(t, h, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
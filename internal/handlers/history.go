@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go-todo-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// changeContext returns the request's context, attributing the task
+// changes made with it to the authenticated user and the request ID.
+func changeContext(c *gin.Context) context.Context {
+	return store.WithActor(c.Request.Context(), store.Actor{
+		UserID:    c.GetUint("userID"),
+		RequestID: c.GetString("requestID"),
+	})
+}
+
+// GetHistory returns the changes made to a task, oldest first. The history
+// stays available once the task is in the trash or purged from it.
+func (h *TaskHandler) GetHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	events, err := h.tasks.History(c.Request.Context(), userID.(uint), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching history"})
+		return
+	}
+
+	// Tasks created before the history was recorded have none yet.
+	if len(events) == 0 {
+		if _, ok := h.findTask(c, userID.(uint)); !ok {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, events)
+}
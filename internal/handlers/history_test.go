@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/middleware"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupHistoryRouter serves the task routes for user 1, with request IDs.
+func setupHistoryRouter(t *testing.T) *gin.Engine {
+	h := handlers.NewTaskHandler(store.NewGormStores(testutils.SetupTestDB(t), 0))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(middleware.RequestID())
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.POST("/tasks", h.CreateTask)
+	r.PUT("/tasks/:id", h.UpdateTask)
+	r.DELETE("/tasks/:id", h.DeleteTask)
+	r.POST("/tasks/:id/restore", h.RestoreTask)
+	r.DELETE("/trash/:id", h.PurgeTask)
+	r.GET("/tasks/:id/history", h.GetHistory)
+
+	return r
+}
+
+func TestGetHistory(t *testing.T) {
+	r := setupHistoryRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Write report"}`)
+
+	req, _ := http.NewRequest(http.MethodPut, "/tasks/1", strings.NewReader(`{"title":"Write the report","done":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.RequestIDHeader, "req-update")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "req-update", w.Header().Get(middleware.RequestIDHeader))
+
+	w = serve(r, http.MethodGet, "/tasks/1/history", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var events []models.TaskEvent
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &events))
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.TaskCreated, events[0].Action)
+		assert.Len(t, events[0].RequestID, 32)
+
+		assert.Equal(t, models.TaskUpdated, events[1].Action)
+		assert.Equal(t, uint(1), events[1].ActorID)
+		assert.Equal(t, "req-update", events[1].RequestID)
+		assert.Equal(t, models.FieldChange{From: "Write report", To: "Write the report"}, events[1].Changes["title"])
+		assert.Equal(t, models.FieldChange{From: false, To: true}, events[1].Changes["done"])
+		assert.NotContains(t, events[1].Changes, "description")
+	}
+
+	serve(r, http.MethodDelete, "/tasks/1", "")
+	w = serve(r, http.MethodGet, "/tasks/1/history", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &events)
+	assert.Len(t, events, 3)
+
+	serve(r, http.MethodPost, "/tasks/1/restore", "")
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks/1/history", "").Body.Bytes(), &events)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, models.TaskDeleted, events[2].Action)
+		assert.Equal(t, models.TaskRestored, events[3].Action)
+	}
+
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/99/history", "").Code)
+}
+
+func TestGetHistoryAfterPurge(t *testing.T) {
+	r := setupHistoryRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Write report"}`)
+	serve(r, http.MethodDelete, "/tasks/1", "")
+
+	req, _ := http.NewRequest(http.MethodDelete, "/trash/1", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-purge")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(r, http.MethodGet, "/tasks/1/history", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var events []models.TaskEvent
+	json.Unmarshal(w.Body.Bytes(), &events)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, models.TaskDeleted, events[1].Action)
+		assert.Equal(t, models.TaskPurged, events[2].Action)
+		assert.Equal(t, uint(1), events[2].ActorID)
+		assert.Equal(t, "req-purge", events[2].RequestID)
+	}
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var projectColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ProjectHandler serves the project endpoints.
+type ProjectHandler struct {
+	projects store.ProjectStore
+}
+
+func NewProjectHandler(projects store.ProjectStore) *ProjectHandler {
+	return &ProjectHandler{projects: projects}
+}
+
+// projectInput is the request body for creating and replacing a project.
+// A missing position appends the project after the user's others.
+type projectInput struct {
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Archived bool   `json:"archived"`
+	Position *int   `json:"position"`
+}
+
+func (input *projectInput) validate() error {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if input.Color != "" && !projectColorPattern.MatchString(input.Color) {
+		return errors.New("color must be a hex colour like #1e90ff")
+	}
+	if input.Position != nil && *input.Position < 1 {
+		return errors.New("position must be at least 1")
+	}
+	return nil
+}
+
+// findProject loads the project named by the :id parameter for the current
+// user. It writes the error response and returns false if there is none.
+func findProject(c *gin.Context, projects store.ProjectStore, userID uint) (models.Project, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return models.Project{}, false
+	}
+
+	project, err := projects.Get(c.Request.Context(), userID, uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return project, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching project"})
+		return project, false
+	}
+
+	return project, true
+}
+
+// GetProjects lists the user's projects in position order. Archived projects
+// are only included with ?archived=true.
+func (h *ProjectHandler) GetProjects(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	includeArchived := false
+	if value := c.Query("archived"); value != "" {
+		var err error
+		includeArchived, err = strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid archived parameter: must be true or false"})
+			return
+		}
+	}
+
+	projects, err := h.projects.List(c.Request.Context(), userID.(uint), includeArchived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching projects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+func (h *ProjectHandler) GetProject(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	project, ok := findProject(c, h.projects, userID.(uint))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) CreateProject(c *gin.Context) {
+	var input projectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project := models.Project{
+		Name:     input.Name,
+		Color:    input.Color,
+		Archived: input.Archived,
+		UserID:   userID.(uint),
+	}
+	if input.Position != nil {
+		project.Position = *input.Position
+	}
+
+	if err := h.projects.Create(c.Request.Context(), &project); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+func (h *ProjectHandler) UpdateProject(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	project, ok := findProject(c, h.projects, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var input projectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project.Name = input.Name
+	project.Color = input.Color
+	project.Archived = input.Archived
+	if input.Position != nil {
+		project.Position = *input.Position
+	}
+
+	if err := h.projects.Update(c.Request.Context(), &project); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject removes a project. Its tasks move to the Inbox unless
+// ?tasks=delete is given, in which case they are deleted with it.
+func (h *ProjectHandler) DeleteProject(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var deleteTasks bool
+	switch c.DefaultQuery("tasks", "inbox") {
+	case "inbox":
+	case "delete":
+		deleteTasks = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tasks parameter: must be inbox or delete"})
+		return
+	}
+
+	project, ok := findProject(c, h.projects, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.projects.Delete(changeContext(c), &project, deleteTasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted"})
+}
@@ -0,0 +1,170 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setupProjectRouter serves the project and task routes for user 1.
+func setupProjectRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	database := testutils.SetupTestDB(t)
+	stores := store.NewGormStores(database, 0)
+	projects := handlers.NewProjectHandler(stores.Projects)
+	tasks := handlers.NewTaskHandler(stores)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.GET("/projects", projects.GetProjects)
+	r.POST("/projects", projects.CreateProject)
+	r.GET("/projects/:id", projects.GetProject)
+	r.PUT("/projects/:id", projects.UpdateProject)
+	r.DELETE("/projects/:id", projects.DeleteProject)
+	r.GET("/projects/:id/tasks", tasks.GetProjectTasks)
+	r.GET("/tasks", tasks.GetTasks)
+	r.POST("/tasks", tasks.CreateTask)
+	r.PUT("/tasks/:id", tasks.UpdateTask)
+	r.PATCH("/tasks/:id", tasks.PatchTask)
+
+	return r, database
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateAndListProjects(t *testing.T) {
+	r, database := setupProjectRouter(t)
+	database.Create(&models.Project{Name: "Someone else's", Position: 1, UserID: 2})
+
+	w := serve(r, http.MethodPost, "/projects", `{"name":" Work ","color":"#1E90FF"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var project models.Project
+	json.Unmarshal(w.Body.Bytes(), &project)
+	assert.Equal(t, "Work", project.Name)
+	assert.Equal(t, 1, project.Position)
+
+	w = serve(r, http.MethodPost, "/projects", `{"name":"Archive","archived":true}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var projects []models.Project
+	w = serve(r, http.MethodGet, "/projects", "")
+	json.Unmarshal(w.Body.Bytes(), &projects)
+	assert.Len(t, projects, 1)
+
+	w = serve(r, http.MethodGet, "/projects?archived=true", "")
+	json.Unmarshal(w.Body.Bytes(), &projects)
+	assert.Len(t, projects, 2)
+	assert.Equal(t, 2, projects[1].Position)
+}
+
+func TestCreateProjectValidation(t *testing.T) {
+	r, _ := setupProjectRouter(t)
+
+	for _, body := range []string{
+		`{"name":"  "}`,
+		`{"name":"Work","color":"blue"}`,
+		`{"name":"Work","position":0}`,
+		`{"name":`,
+	} {
+		w := serve(r, http.MethodPost, "/projects", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestGetProjectOtherUser(t *testing.T) {
+	r, database := setupProjectRouter(t)
+	database.Create(&models.Project{Name: "Private", UserID: 2})
+
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/projects/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/projects/1/tasks", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPut, "/projects/1", `{"name":"Mine"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodDelete, "/projects/1", "").Code)
+}
+
+func TestProjectTasks(t *testing.T) {
+	r, database := setupProjectRouter(t)
+	database.Create(&models.Project{Name: "Work", Position: 1, UserID: 1})
+	database.Create(&models.Project{Name: "Private", Position: 1, UserID: 2})
+	database.Create(&models.Project{Name: "Old", Position: 2, Archived: true, UserID: 1})
+
+	assert.Equal(t, http.StatusCreated, serve(r, http.MethodPost, "/tasks", `{"title":"In work","project_id":1}`).Code)
+	assert.Equal(t, http.StatusCreated, serve(r, http.MethodPost, "/tasks", `{"title":"In inbox"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Foreign","project_id":2}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Archived","project_id":3}`).Code)
+
+	var page struct {
+		Data []models.Task `json:"data"`
+	}
+
+	w := serve(r, http.MethodGet, "/projects/1/tasks", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "In work", page.Data[0].Title)
+
+	w = serve(r, http.MethodGet, "/tasks?project_id=inbox", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "In inbox", page.Data[0].Title)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodGet, "/tasks?project_id=work", "").Code)
+
+	// Move the inbox task into the project.
+	w = serve(r, http.MethodPatch, "/tasks/2", `{"project_id":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(r, http.MethodGet, "/tasks?project_id=1", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Len(t, page.Data, 2)
+
+	// And back to the inbox with a full update.
+	w = serve(r, http.MethodPut, "/tasks/2", `{"title":"In inbox","project_id":null}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var task models.Task
+	database.First(&task, 2)
+	assert.Nil(t, task.ProjectID)
+}
+
+func TestDeleteProject(t *testing.T) {
+	r, database := setupProjectRouter(t)
+	database.Create(&models.Project{Name: "Keep", Position: 1, UserID: 1})
+	database.Create(&models.Project{Name: "Drop", Position: 2, UserID: 1})
+	keep, drop := uint(1), uint(2)
+	database.Create(&models.Task{Title: "kept", ProjectID: &keep, Position: "1", UserID: 1})
+	database.Create(&models.Task{Title: "dropped", ProjectID: &drop, Position: "2", UserID: 1})
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodDelete, "/projects/1?tasks=archive", "").Code)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/projects/1", "").Code)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/projects/2?tasks=delete", "").Code)
+
+	var tasks []models.Task
+	database.Find(&tasks)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "kept", tasks[0].Title)
+	assert.Nil(t, tasks[0].ProjectID)
+
+	var count int64
+	database.Model(&models.Project{}).Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/recurrence"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 100
+)
+
+// occurrenceTime is the date a task's recurrence follows: its due date, or
+// its start date when it has none.
+func occurrenceTime(task *models.Task) *time.Time {
+	if task.DueAt != nil {
+		return task.DueAt
+	}
+	return task.StartAt
+}
+
+// prepareRecurrence checks task.RRule and task.RRuleTZ and sets the start
+// of the series. Recurring tasks are only completed by hand, so they cannot
+// follow their subtasks or checklist. The start is kept from old while the rule is unchanged and
+// otherwise restarts at the task's current occurrence. old is nil for new
+// tasks.
+func prepareRecurrence(old, task *models.Task) error {
+	if task.RRule == "" {
+		task.RRuleStart = nil
+		task.RRuleTZ = ""
+		return nil
+	}
+
+	rule, err := recurrence.Normalize(task.RRule)
+	if err != nil {
+		return err
+	}
+	task.RRule = rule
+
+	if task.AutoComplete {
+		return errors.New("a recurring task cannot follow its subtasks")
+	}
+	if task.ChecklistAutoComplete {
+		return errors.New("a recurring task cannot follow its checklist")
+	}
+
+	if _, err := recurrence.LoadLocation(task.RRuleTZ); err != nil {
+		return err
+	}
+
+	anchor := occurrenceTime(task)
+	if anchor == nil {
+		return errors.New("a recurring task needs a due_at or start_at")
+	}
+
+	if old != nil && old.RRule == rule && old.RRuleStart != nil {
+		task.RRuleStart = old.RRuleStart
+	} else {
+		start := *anchor
+		task.RRuleStart = &start
+	}
+	return nil
+}
+
+// advanceOccurrence records the current occurrence of a recurring task as
+// completed and moves the task on to the next one, shifting its dates. When
+// the rule has no further occurrences the task stays done.
+func advanceOccurrence(task *models.Task, completedAt time.Time) (*models.TaskOccurrence, error) {
+	occurrence := &models.TaskOccurrence{
+		StartAt:     task.StartAt,
+		DueAt:       task.DueAt,
+		CompletedAt: completedAt,
+	}
+
+	loc, err := recurrence.LoadLocation(task.RRuleTZ)
+	if err != nil {
+		return occurrence, err
+	}
+
+	anchor := *occurrenceTime(task)
+	next, ok, err := recurrence.Next(task.RRule, task.RRuleStart.In(loc), anchor)
+	if err != nil || !ok {
+		return occurrence, err
+	}
+
+	shift := next.Sub(anchor)
+	if task.StartAt != nil {
+		startAt := task.StartAt.Add(shift)
+		task.StartAt = &startAt
+	}
+	if task.DueAt != nil {
+		dueAt := task.DueAt.Add(shift)
+		task.DueAt = &dueAt
+	}
+	task.Done = false
+
+	return occurrence, nil
+}
+
+// saveTask stores an updated task. Completing a recurring task moves it on
+// to its next occurrence instead.
+func (h *TaskHandler) saveTask(ctx context.Context, old models.Task, task *models.Task) error {
+	if old.Done || !task.Done || task.RRule == "" {
+		return h.tasks.Update(ctx, task)
+	}
+
+	occurrence, err := advanceOccurrence(task, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return h.tasks.CompleteOccurrence(ctx, task, occurrence)
+}
+
+// GetOccurrences lists the completed occurrences of a recurring task, most
+// recent first.
+func (h *TaskHandler) GetOccurrences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	occurrences, err := h.tasks.Occurrences(c.Request.Context(), task.UserID, task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching occurrences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, occurrences)
+}
+
+type recurrencePreviewInput struct {
+	RRule string     `json:"rrule" binding:"required"`
+	TZ    string     `json:"tz"`
+	Start *time.Time `json:"start"`
+	Count int        `json:"count"`
+}
+
+// PreviewRecurrence lists the next occurrences of a rule from start, which
+// defaults to now. The rule is expanded in tz, which defaults to UTC.
+func (h *TaskHandler) PreviewRecurrence(c *gin.Context) {
+	var input recurrencePreviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Count == 0 {
+		input.Count = defaultPreviewCount
+	}
+	if input.Count < 0 || input.Count > maxPreviewCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 100"})
+		return
+	}
+
+	loc, err := recurrence.LoadLocation(input.TZ)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start := time.Now().Truncate(time.Second)
+	if input.Start != nil {
+		start = *input.Start
+	}
+	start = start.In(loc)
+
+	rule, err := recurrence.Normalize(input.RRule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	occurrences, err := recurrence.Preview(rule, start, input.Count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rrule": rule, "occurrences": occurrences})
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRecurrenceRouter(t *testing.T) *gin.Engine {
+	h := handlers.NewTaskHandler(store.NewGormStores(testutils.SetupTestDB(t), 0))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.POST("/tasks", h.CreateTask)
+	r.GET("/tasks/:id", h.GetTask)
+	r.PUT("/tasks/:id", h.UpdateTask)
+	r.PATCH("/tasks/:id", h.PatchTask)
+	r.GET("/tasks/:id/occurrences", h.GetOccurrences)
+	r.POST("/recurrence/preview", h.PreviewRecurrence)
+
+	return r
+}
+
+func TestCompleteRecurringTask(t *testing.T) {
+	r := setupRecurrenceRouter(t)
+
+	w := serve(r, http.MethodPost, "/tasks", `{"title":"Invoice","rrule":"rrule:freq=monthly;count=3","start_at":"2025-01-25T09:00:00Z","due_at":"2025-01-31T17:00:00Z"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var task models.Task
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, "FREQ=MONTHLY;COUNT=3", task.RRule)
+
+	// January 31st is followed by March 31st, as February has no 31st.
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"done":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.False(t, task.Done)
+	assert.Equal(t, time.Date(2025, 3, 31, 17, 0, 0, 0, time.UTC), task.DueAt.UTC())
+	assert.Equal(t, time.Date(2025, 3, 25, 9, 0, 0, 0, time.UTC), task.StartAt.UTC())
+
+	w = serve(r, http.MethodPut, "/tasks/1", `{"title":"Invoice","done":true,"rrule":"FREQ=MONTHLY;COUNT=3","start_at":"2025-03-25T09:00:00Z","due_at":"2025-03-31T17:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, time.Date(2025, 5, 31, 17, 0, 0, 0, time.UTC), task.DueAt.UTC())
+
+	// The third occurrence is the last one, so the task stays done.
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"done":true}`)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.True(t, task.Done)
+
+	var occurrences []models.TaskOccurrence
+	w = serve(r, http.MethodGet, "/tasks/1/occurrences", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &occurrences)
+	assert.Len(t, occurrences, 3)
+	assert.Equal(t, time.Date(2025, 5, 31, 17, 0, 0, 0, time.UTC), occurrences[0].DueAt.UTC())
+	assert.Equal(t, time.Date(2025, 1, 31, 17, 0, 0, 0, time.UTC), occurrences[2].DueAt.UTC())
+}
+
+func TestCompleteRecurringTaskInTimezone(t *testing.T) {
+	r := setupRecurrenceRouter(t)
+
+	// Mondays at 08:30 in New York, where clocks go forward on 2025-03-09.
+	w := serve(r, http.MethodPost, "/tasks", `{"title":"Standup","rrule":"FREQ=WEEKLY;BYDAY=MO","rrule_tz":"America/New_York","due_at":"2025-03-03T08:30:00-05:00"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var task models.Task
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, "America/New_York", task.RRuleTZ)
+
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"done":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC), task.DueAt.UTC())
+
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"done":true}`)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, time.Date(2025, 3, 17, 12, 30, 0, 0, time.UTC), task.DueAt.UTC())
+
+	// Dropping the rule drops its time zone.
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"rrule":""}`)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Empty(t, task.RRuleTZ)
+}
+
+func TestRecurringTaskValidation(t *testing.T) {
+	r := setupRecurrenceRouter(t)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"No dates","rrule":"FREQ=DAILY"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Bad rule","rrule":"FREQ=SOMETIMES","due_at":"2025-01-01T00:00:00Z"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Bad zone","rrule":"FREQ=DAILY","rrule_tz":"Mars/Olympus_Mons","due_at":"2025-01-01T00:00:00Z"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/9/occurrences", "").Code)
+}
+
+func TestPreviewRecurrence(t *testing.T) {
+	r := setupRecurrenceRouter(t)
+
+	w := serve(r, http.MethodPost, "/recurrence/preview", `{"rrule":"FREQ=WEEKLY;BYDAY=MO,TH","start":"2025-01-01T08:00:00Z","count":3}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var preview struct {
+		RRule       string      `json:"rrule"`
+		Occurrences []time.Time `json:"occurrences"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	assert.Equal(t, []time.Time{
+		time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 9, 8, 0, 0, 0, time.UTC),
+	}, preview.Occurrences)
+
+	// Occurrences keep their local hour across the DST change.
+	w = serve(r, http.MethodPost, "/recurrence/preview", `{"rrule":"FREQ=WEEKLY;BYDAY=MO","tz":"America/New_York","start":"2025-03-03T13:30:00Z","count":2}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	if assert.Len(t, preview.Occurrences, 2) {
+		assert.Equal(t, time.Date(2025, 3, 3, 13, 30, 0, 0, time.UTC), preview.Occurrences[0].UTC())
+		assert.Equal(t, time.Date(2025, 3, 10, 12, 30, 0, 0, time.UTC), preview.Occurrences[1].UTC())
+	}
+
+	w = serve(r, http.MethodPost, "/recurrence/preview", `{"rrule":"FREQ=DAILY"}`)
+	json.Unmarshal(w.Body.Bytes(), &preview)
+	assert.Len(t, preview.Occurrences, 5)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/recurrence/preview", `{"rrule":"FREQ=DAILY","count":1000}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/recurrence/preview", `{"rrule":"DAILY"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/recurrence/preview", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/recurrence/preview", `{"rrule":"FREQ=DAILY","tz":"Nowhere"}`).Code)
+}
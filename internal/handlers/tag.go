@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxTagNameLength = 50
+
+// normalizeTagName trims and lowercases a tag name, so "Urgent " and
+// "urgent" are the same tag. Commas are reserved as the separator of the
+// tags filter.
+func normalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	switch {
+	case name == "":
+		return "", errors.New("tag names must not be empty")
+	case utf8.RuneCountInString(name) > maxTagNameLength:
+		return "", fmt.Errorf("tag names must be at most %d characters", maxTagNameLength)
+	case strings.Contains(name, ","):
+		return "", errors.New("tag names must not contain commas")
+	}
+
+	return name, nil
+}
+
+// normalizeTaskTags normalises the names in task.Tags.
+func normalizeTaskTags(task *models.Task) error {
+	for i, name := range task.Tags {
+		normalized, err := normalizeTagName(name)
+		if err != nil {
+			return err
+		}
+		task.Tags[i] = normalized
+	}
+	return nil
+}
+
+// TagHandler serves the tag endpoints.
+type TagHandler struct {
+	tags store.TagStore
+}
+
+func NewTagHandler(tags store.TagStore) *TagHandler {
+	return &TagHandler{tags: tags}
+}
+
+type tagInput struct {
+	Name string `json:"name"`
+}
+
+type mergeTagInput struct {
+	Into uint `json:"into" binding:"required"`
+}
+
+// findTag loads the tag named by the :id parameter for the current user.
+// It writes the error response and returns false if there is none.
+func (h *TagHandler) findTag(c *gin.Context, userID uint) (models.Tag, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return models.Tag{}, false
+	}
+
+	tag, err := h.tags.Get(c.Request.Context(), userID, uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return tag, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tag"})
+		return tag, false
+	}
+
+	return tag, true
+}
+
+// GetTags lists the user's tags by name with the number of tasks carrying
+// each.
+func (h *TagHandler) GetTags(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tags, err := h.tags.List(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var input tagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	name, err := normalizeTagName(input.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag := models.Tag{Name: name, UserID: userID.(uint)}
+	err = h.tags.Create(c.Request.Context(), &tag)
+	if errors.Is(err, store.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag renames a tag. Renaming to the name of another tag is a
+// conflict; merge the tags instead.
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tag, ok := h.findTag(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var input tagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name, err := normalizeTagName(input.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag.Name = name
+	err = h.tags.Update(changeContext(c), &tag)
+	if errors.Is(err, store.ErrDuplicate) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists; merge the tags instead"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// MergeTag moves the tasks of the tag named by :id to the tag given as
+// "into" and deletes the former.
+func (h *TagHandler) MergeTag(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	source, ok := h.findTag(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var input mergeTagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Into == source.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a tag into itself"})
+		return
+	}
+
+	target, err := h.tags.Get(c.Request.Context(), source.UserID, input.Into)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "into does not refer to one of your tags"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tag"})
+		return
+	}
+
+	if err := h.tags.Merge(changeContext(c), &source, &target); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error merging tags"})
+		return
+	}
+
+	target, err = h.tags.Get(c.Request.Context(), target.UserID, target.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// DeleteTag deletes a tag and detaches it from its tasks.
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tag, ok := h.findTag(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.tags.Delete(changeContext(c), &tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted"})
+}
@@ -0,0 +1,140 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTagRouter serves the tag and task routes for user 1.
+func setupTagRouter(t *testing.T) *gin.Engine {
+	stores := store.NewGormStores(testutils.SetupTestDB(t), 0)
+	tags := handlers.NewTagHandler(stores.Tags)
+	tasks := handlers.NewTaskHandler(stores)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.GET("/tags", tags.GetTags)
+	r.POST("/tags", tags.CreateTag)
+	r.PUT("/tags/:id", tags.UpdateTag)
+	r.POST("/tags/:id/merge", tags.MergeTag)
+	r.DELETE("/tags/:id", tags.DeleteTag)
+	r.GET("/tasks", tasks.GetTasks)
+	r.POST("/tasks", tasks.CreateTask)
+	r.GET("/tasks/:id", tasks.GetTask)
+	r.PUT("/tasks/:id", tasks.UpdateTask)
+	r.PATCH("/tasks/:id", tasks.PatchTask)
+
+	return r
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func listTags(t *testing.T, r *gin.Engine) map[string]models.Tag {
+	w := serve(r, http.MethodGet, "/tags", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tags []models.Tag
+	json.Unmarshal(w.Body.Bytes(), &tags)
+
+	result := map[string]models.Tag{}
+	for _, tag := range tags {
+		result[tag.Name] = tag
+	}
+	return result
+}
+
+func TestTaskTags(t *testing.T) {
+	r := setupTagRouter(t)
+
+	w := serve(r, http.MethodPost, "/tasks", `{"title":"Fix login","tags":["Urgent"," backend","urgent"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var task models.Task
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, []string{"backend", "urgent"}, task.Tags)
+
+	assert.Equal(t, http.StatusCreated, serve(r, http.MethodPost, "/tasks", `{"title":"Call client","tags":["urgent"]}`).Code)
+	assert.Equal(t, http.StatusCreated, serve(r, http.MethodPost, "/tasks", `{"title":"Untagged"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Bad","tags":["a,b"]}`).Code)
+
+	var page struct {
+		Data []models.Task `json:"data"`
+	}
+
+	w = serve(r, http.MethodGet, "/tasks?tags=backend,urgent", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Len(t, page.Data, 2)
+
+	w = serve(r, http.MethodGet, "/tasks?tags=backend,urgent&tag_mode=all", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, "Fix login", page.Data[0].Title)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodGet, "/tasks?tags=urgent&tag_mode=some", "").Code)
+
+	// Detach a tag with a merge patch and attach another with a full update.
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"tags":["urgent"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serve(r, http.MethodPut, "/tasks/2", `{"title":"Call client","tags":["urgent","waiting-on-client"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	tags := listTags(t, r)
+	assert.Equal(t, int64(0), tags["backend"].TaskCount)
+	assert.Equal(t, int64(2), tags["urgent"].TaskCount)
+	assert.Equal(t, int64(1), tags["waiting-on-client"].TaskCount)
+}
+
+func TestTagEndpoints(t *testing.T) {
+	r := setupTagRouter(t)
+	serve(r, http.MethodPost, "/tasks", `{"title":"One","tags":["bug"]}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Two","tags":["defect"]}`)
+
+	assert.Equal(t, http.StatusCreated, serve(r, http.MethodPost, "/tags", `{"name":"Later"}`).Code)
+	assert.Equal(t, http.StatusConflict, serve(r, http.MethodPost, "/tags", `{"name":"later"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tags", `{"name":" "}`).Code)
+
+	tags := listTags(t, r)
+	bug, defect, later := tags["bug"], tags["defect"], tags["later"]
+
+	assert.Equal(t, http.StatusConflict, serve(r, http.MethodPut, "/tags/"+itoa(later.ID), `{"name":"bug"}`).Code)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodPut, "/tags/"+itoa(later.ID), `{"name":"someday"}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPut, "/tags/99", `{"name":"x"}`).Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tags/"+itoa(defect.ID)+"/merge", `{"into":`+itoa(defect.ID)+`}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tags/"+itoa(defect.ID)+"/merge", `{"into":99}`).Code)
+
+	w := serve(r, http.MethodPost, "/tags/"+itoa(defect.ID)+"/merge", `{"into":`+itoa(bug.ID)+`}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var merged models.Tag
+	json.Unmarshal(w.Body.Bytes(), &merged)
+	assert.Equal(t, "bug", merged.Name)
+	assert.Equal(t, int64(2), merged.TaskCount)
+
+	w = serve(r, http.MethodGet, "/tasks/2", "")
+	var task models.Task
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, []string{"bug"}, task.Tags)
+	assert.Equal(t, uint(2), task.Version)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/tags/"+itoa(bug.ID), "").Code)
+
+	tags = listTags(t, r)
+	assert.Len(t, tags, 1)
+	assert.Contains(t, tags, "someday")
+}
@@ -1,16 +1,186 @@
 package handlers
 
 import (
-	"go-todo-api/internal/db"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/utils"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func GetTasks(c *gin.Context) {
-	var tasks []models.Task
+const (
+	dateLayout     = "2006-01-02"
+	lowestPriority = 4
+)
+
+// normalizeTaskDates stores start/due dates in UTC and checks they are ordered.
+func normalizeTaskDates(task *models.Task) error {
+	if task.StartAt != nil {
+		startAt := task.StartAt.UTC()
+		task.StartAt = &startAt
+	}
+	if task.DueAt != nil {
+		dueAt := task.DueAt.UTC()
+		task.DueAt = &dueAt
+	}
+
+	if task.StartAt != nil && task.DueAt != nil && task.StartAt.After(*task.DueAt) {
+		return errors.New("start_at must not be after due_at")
+	}
+
+	return nil
+}
+
+// parseTimeParam accepts RFC3339 timestamps or plain dates, which are
+// interpreted in loc.
+func parseTimeParam(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	t, err := time.ParseInLocation(dateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.UTC(), nil
+}
+
+// TaskHandler serves the task endpoints.
+type TaskHandler struct {
+	tasks      store.TaskStore
+	projects   store.ProjectStore
+	comments   store.CommentStore
+	checklists store.ChecklistStore
+}
+
+func NewTaskHandler(stores store.Stores) *TaskHandler {
+	return &TaskHandler{
+		tasks:      stores.Tasks,
+		projects:   stores.Projects,
+		comments:   stores.Comments,
+		checklists: stores.Checklists,
+	}
+}
+
+// normalizeTaskPriority defaults a missing priority to the lowest one and
+// checks the range.
+func normalizeTaskPriority(task *models.Task) error {
+	if task.Priority == 0 {
+		task.Priority = lowestPriority
+	}
+	if task.Priority < 1 || task.Priority > lowestPriority {
+		return errors.New("priority must be between 1 (highest) and 4")
+	}
+	return nil
+}
+
+// checkProject verifies that a task may be moved from project current to
+// next: next must be one of the user's projects and, unless the task is
+// already there, not archived. It writes the error response and returns
+// false otherwise.
+func (h *TaskHandler) checkProject(c *gin.Context, userID uint, current, next *uint) bool {
+	if next == nil || (current != nil && *current == *next) {
+		return true
+	}
+
+	project, err := h.projects.Get(c.Request.Context(), userID, *next)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id does not refer to one of your projects"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching project"})
+		return false
+	}
+
+	if project.Archived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move tasks into an archived project"})
+		return false
+	}
+
+	return true
+}
+
+// checkParent verifies that task may be made a subtask of next: next must
+// be one of the user's tasks and neither task itself nor one of its
+// descendants. It writes the error response and returns false otherwise.
+func (h *TaskHandler) checkParent(c *gin.Context, task models.Task, next *uint) bool {
+	if next == nil || (task.ParentID != nil && *task.ParentID == *next) {
+		return true
+	}
+
+	for id := next; id != nil; {
+		if *id == task.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A task cannot be a subtask of itself or of its own subtasks"})
+			return false
+		}
+
+		parent, err := h.tasks.Get(c.Request.Context(), task.UserID, *id)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "parent_id does not refer to one of your tasks"})
+			return false
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching task"})
+			return false
+		}
+		id = parent.ParentID
+	}
 
+	return true
+}
+
+// findTask loads the task named by the :id parameter for the current user.
+// It writes the error response and returns false if there is none.
+func (h *TaskHandler) findTask(c *gin.Context, userID uint) (models.Task, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return models.Task{}, false
+	}
+
+	task, err := h.tasks.Get(c.Request.Context(), userID, uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return task, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching task"})
+		return task, false
+	}
+
+	return task, true
+}
+
+func (h *TaskHandler) GetTasks(c *gin.Context) {
+	value, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	filter, err := parseTaskFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.listTasks(c, value.(uint), filter)
+}
+
+// GetProjectTasks lists the tasks of one project, accepting the same
+// parameters as GetTasks.
+func (h *TaskHandler) GetProjectTasks(c *gin.Context) {
 	value, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -19,15 +189,160 @@ func GetTasks(c *gin.Context) {
 
 	userID := value.(uint)
 
-	if err := db.DB.Where("user_id = ?", userID).Find(&tasks).Error; err != nil {
+	project, ok := findProject(c, h.projects, userID)
+	if !ok {
+		return
+	}
+
+	filter, err := parseTaskFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.ProjectID = &project.ID
+	filter.InboxOnly = false
+
+	h.listTasks(c, userID, filter)
+}
+
+// listTasks writes the page of tasks selected by filter and the pagination
+// parameters.
+func (h *TaskHandler) listTasks(c *gin.Context, userID uint, filter store.TaskFilter) {
+	params, err := parseTaskListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.tasks.List(c.Request.Context(), userID, params.options(filter))
+	if errors.Is(err, store.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+
+	page := taskPage{Data: list.Tasks, Total: list.Total, Limit: params.Limit, Offset: params.Offset}
+	if list.HasMore {
+		last := list.Tasks[len(list.Tasks)-1]
+		next := encodeCursor(taskCursor{
+			Sort:  params.Sort,
+			Desc:  params.Desc,
+			Value: store.TaskSortValue(last, params.Sort),
+			ID:    last.ID,
+		})
+		page.NextCursor = &next
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *TaskHandler) GetOverdueTasks(c *gin.Context) {
+	value, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	list, err := h.tasks.List(c.Request.Context(), value.(uint), store.TaskListOptions{
+		Filter: store.TaskFilter{Overdue: boolPtr(true), Now: time.Now().UTC()},
+		Sort:   "due_at",
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, list.Tasks)
+}
+
+// taskTree is a task with its subtasks nested below it.
+type taskTree struct {
+	models.Task
+	Children []*taskTree `json:"children"`
+}
+
+// GetSubtree returns a task with all its descendants nested as children.
+func (h *TaskHandler) GetSubtree(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	tasks, err := h.tasks.Subtree(c.Request.Context(), userID.(uint), uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tasks"})
+		return
+	}
+
+	nodes := make(map[uint]*taskTree, len(tasks))
+	for _, task := range tasks {
+		nodes[task.ID] = &taskTree{Task: task, Children: []*taskTree{}}
+	}
+	for _, task := range tasks {
+		if task.ID != uint(id) {
+			parent := nodes[*task.ParentID]
+			parent.Children = append(parent.Children, nodes[task.ID])
+		}
+	}
+
+	c.JSON(http.StatusOK, nodes[uint(id)])
+}
+
+// taskETag identifies a specific version of a task for conditional requests.
+func taskETag(task models.Task) string {
+	return fmt.Sprintf(`"%d-%d"`, task.ID, task.Version)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison required by RFC 9110.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *TaskHandler) GetTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	etag := taskETag(task)
+	c.Header("ETag", etag)
+
+	if header := c.GetHeader("If-None-Match"); header != "" && etagMatches(header, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
 }
 
-func CreateTask(c *gin.Context) {
+func (h *TaskHandler) CreateTask(c *gin.Context) {
 	var task models.Task
 	if err := c.ShouldBindJSON(&task); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -41,8 +356,39 @@ func CreateTask(c *gin.Context) {
 	}
 
 	task.UserID = userID.(uint)
+	task.Version = 1
+	task.Position = ""
+	task.DeletedAt = gorm.DeletedAt{}
+
+	if err := normalizeTaskDates(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeTaskTags(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := db.DB.Create(&task).Error; err != nil {
+	if err := normalizeTaskPriority(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.checkProject(c, task.UserID, nil, task.ProjectID) {
+		return
+	}
+
+	if !h.checkParent(c, models.Task{UserID: task.UserID}, task.ParentID) {
+		return
+	}
+
+	if err := prepareRecurrence(nil, &task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tasks.Create(changeContext(c), &task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating task"})
 		return
 	}
@@ -50,21 +396,18 @@ func CreateTask(c *gin.Context) {
 	c.JSON(http.StatusCreated, task)
 }
 
-func UpdateTask(c *gin.Context) {
-	var task models.Task
-
+func (h *TaskHandler) UpdateTask(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	id := c.Param("id")
-
-	if err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&task).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
 		return
 	}
+	old := task
 
 	var input models.Task
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -75,32 +418,280 @@ func UpdateTask(c *gin.Context) {
 	task.Title = input.Title
 	task.Description = input.Description
 	task.Done = input.Done
+	task.StartAt = input.StartAt
+	task.DueAt = input.DueAt
+	task.Tags = input.Tags
+	task.Priority = input.Priority
 
-	if err := db.DB.Save(&task).Error; err != nil {
+	if err := normalizeTaskDates(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeTaskTags(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeTaskPriority(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.checkProject(c, task.UserID, task.ProjectID, input.ProjectID) {
+		return
+	}
+	task.ProjectID = input.ProjectID
+
+	if !h.checkParent(c, task, input.ParentID) {
+		return
+	}
+	task.ParentID = input.ParentID
+	task.AutoComplete = input.AutoComplete
+	task.ChecklistAutoComplete = input.ChecklistAutoComplete
+
+	task.RRule = input.RRule
+	task.RRuleTZ = input.RRuleTZ
+	if err := prepareRecurrence(&old, &task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task.Version++
+
+	if err := h.saveTask(changeContext(c), old, &task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating task"})
 		return
 	}
 
-	db.DB.Save(&task)
+	c.Header("ETag", taskETag(task))
 	c.JSON(http.StatusOK, task)
 }
 
-func DeleteTask(c *gin.Context) {
-	var task models.Task
+// readOnlyTaskFields are the members of a task document that the server
+// maintains. Patches may test them but not change them.
+var readOnlyTaskFields = []string{
+	"id", "version", "position", "subtasks", "checklist", "comment_count",
+	"completed_at", "created_at", "updated_at", "deleted_at",
+}
 
+// checkReadOnlyFields returns an error naming the first read-only member
+// that differs between a task document and its patched version. A missing
+// member counts as null.
+func checkReadOnlyFields(original, patched []byte) error {
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return errors.New("patched task must be an object")
+	}
+
+	for _, field := range readOnlyTaskFields {
+		var from, to interface{}
+		if raw, ok := before[field]; ok {
+			if err := json.Unmarshal(raw, &from); err != nil {
+				return err
+			}
+		}
+		if raw, ok := after[field]; ok {
+			if err := json.Unmarshal(raw, &to); err != nil {
+				return fmt.Errorf("invalid %s: %w", field, err)
+			}
+		}
+		if !reflect.DeepEqual(from, to) {
+			return fmt.Errorf("%s is read-only", field)
+		}
+	}
+	return nil
+}
+
+// PatchTask applies a JSON Merge Patch (RFC 7396) or, when sent as
+// application/json-patch+json, a JSON Patch (RFC 6902) to a task.
+func (h *TaskHandler) PatchTask(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	id := c.Param("id")
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
 
-	if err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&task).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	original, err := json.Marshal(task)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating task"})
+		return
+	}
+
+	var patched []byte
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+		patched, err = utils.MergePatch(original, patch)
+	case "application/json-patch+json":
+		patched, err = utils.ApplyJSONPatch(original, patch)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported patch content type"})
+		return
+	}
+
+	if errors.Is(err, utils.ErrPatchTestFailed) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := checkReadOnlyFields(original, patched); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input models.Task
+	if err := json.Unmarshal(patched, &input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.ID = task.ID
+	input.UserID = task.UserID
+	input.Version = task.Version + 1
+	input.Position = task.Position
+	input.DeletedAt = task.DeletedAt
+
+	if strings.TrimSpace(input.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title must not be empty"})
+		return
+	}
+
+	if err := normalizeTaskDates(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeTaskTags(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeTaskPriority(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.checkProject(c, task.UserID, task.ProjectID, input.ProjectID) {
+		return
+	}
+
+	if !h.checkParent(c, task, input.ParentID) {
+		return
+	}
+
+	if err := prepareRecurrence(&task, &input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.saveTask(changeContext(c), task, &input); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating task"})
+		return
+	}
+
+	c.Header("ETag", taskETag(input))
+	c.JSON(http.StatusOK, input)
+}
+
+type moveTaskInput struct {
+	Before *uint `json:"before"`
+	After  *uint `json:"after"`
+}
+
+// MoveTask places a task right before or after another task in the user's
+// manual order, which is used when listing with sort=position.
+func (h *TaskHandler) MoveTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var input moveTaskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if (input.Before == nil) == (input.After == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of before and after is required"})
+		return
+	}
+
+	anchorID := input.Before
+	if input.After != nil {
+		anchorID = input.After
+	}
+	if *anchorID == task.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A task cannot be moved relative to itself"})
+		return
+	}
+
+	task.Version++
+
+	err := h.tasks.Move(changeContext(c), &task, *anchorID, input.After != nil)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The anchor does not refer to one of your tasks"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error moving task"})
+		return
+	}
+
+	c.Header("ETag", taskETag(task))
+	c.JSON(http.StatusOK, task)
+}
+
+func (h *TaskHandler) DeleteTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var deleteSubtasks bool
+	switch c.DefaultQuery("subtasks", "reparent") {
+	case "reparent":
+	case "delete":
+		deleteSubtasks = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subtasks parameter: must be reparent or delete"})
+		return
+	}
+
+	task, ok := h.findTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.tasks.Delete(changeContext(c), &task, deleteSubtasks); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting task"})
 		return
 	}
 
-	db.DB.Delete(&task)
 	c.JSON(http.StatusOK, gin.H{"message": "Task deleted"})
 }
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-todo-api/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTaskLimit = 50
+	maxTaskLimit     = 100
+)
+
+type taskListParams struct {
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+	Cursor *taskCursor
+}
+
+// options converts the parameters to the store's list options.
+func (p taskListParams) options(filter store.TaskFilter) store.TaskListOptions {
+	opts := store.TaskListOptions{
+		Filter: filter,
+		Sort:   p.Sort,
+		Desc:   p.Desc,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	}
+	if p.Cursor != nil {
+		opts.After = &store.TaskCursor{Value: p.Cursor.Value, ID: p.Cursor.ID}
+	}
+	return opts
+}
+
+// taskCursor marks the last row of a page. It is handed to clients as an
+// opaque base64 string and only valid for the sort it was issued with.
+type taskCursor struct {
+	Sort  string  `json:"s"`
+	Desc  bool    `json:"d"`
+	Value *string `json:"v"`
+	ID    uint    `json:"i"`
+}
+
+type taskPage struct {
+	Data       interface{} `json:"data"`
+	Total      int64       `json:"total"`
+	Limit      int         `json:"limit"`
+	Offset     int         `json:"offset"`
+	NextCursor *string     `json:"next_cursor"`
+}
+
+func encodeCursor(cursor taskCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*taskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor taskCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+
+	if _, ok := store.TaskSortFields[cursor.Sort]; !ok {
+		return nil, errors.New("unknown sort field")
+	}
+
+	return &cursor, nil
+}
+
+func parseTaskListParams(c *gin.Context) (taskListParams, error) {
+	params := taskListParams{Sort: "position", Limit: defaultTaskLimit}
+
+	if sort := c.Query("sort"); sort != "" {
+		if _, ok := store.TaskSortFields[sort]; !ok {
+			return params, fmt.Errorf("Invalid sort parameter: must be one of %s", strings.Join(store.TaskSortFieldNames, ", "))
+		}
+		params.Sort = sort
+	}
+
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		return params, errors.New("Invalid order parameter: must be asc or desc")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxTaskLimit {
+			return params, fmt.Errorf("Invalid limit parameter: must be between 1 and %d", maxTaskLimit)
+		}
+		params.Limit = limit
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return params, errors.New("Invalid offset parameter: must be a non-negative integer")
+		}
+		params.Offset = offset
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		if params.Offset != 0 {
+			return params, errors.New("Cursor and offset cannot be combined")
+		}
+
+		cursor, err := decodeCursor(value)
+		if err != nil || cursor.Sort != params.Sort || cursor.Desc != params.Desc {
+			return params, errors.New("Invalid cursor parameter")
+		}
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
+
+// parseTaskFilter reads the filters accepted by GET /tasks.
+func parseTaskFilter(c *gin.Context) (store.TaskFilter, error) {
+	filter := store.TaskFilter{Title: c.Query("title"), Now: time.Now().UTC()}
+
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return filter, errors.New("Invalid tz parameter")
+		}
+		loc = l
+	}
+
+	if value := c.Query("due_before"); value != "" {
+		t, err := parseTimeParam(value, loc)
+		if err != nil {
+			return filter, errors.New("Invalid due_before parameter")
+		}
+		filter.DueBefore = &t
+	}
+
+	if value := c.Query("due_after"); value != "" {
+		t, err := parseTimeParam(value, loc)
+		if err != nil {
+			return filter, errors.New("Invalid due_after parameter")
+		}
+		filter.DueAfter = &t
+	}
+
+	if value := c.Query("updated_since"); value != "" {
+		t, err := parseTimeParam(value, loc)
+		if err != nil {
+			return filter, errors.New("Invalid updated_since parameter")
+		}
+		filter.UpdatedSince = &t
+	}
+
+	switch c.Query("overdue") {
+	case "":
+	case "true":
+		filter.Overdue = boolPtr(true)
+	case "false":
+		filter.Overdue = boolPtr(false)
+	default:
+		return filter, errors.New("Invalid overdue parameter")
+	}
+
+	if value := c.Query("project_id"); value == "inbox" {
+		filter.InboxOnly = true
+	} else if value != "" {
+		id, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return filter, errors.New("Invalid project_id parameter: must be a project id or inbox")
+		}
+		projectID := uint(id)
+		filter.ProjectID = &projectID
+	}
+
+	if value := c.Query("parent_id"); value == "root" {
+		filter.RootOnly = true
+	} else if value != "" {
+		id, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return filter, errors.New("Invalid parent_id parameter: must be a task id or root")
+		}
+		parentID := uint(id)
+		filter.ParentID = &parentID
+	}
+
+	if value := c.Query("tags"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			name, err := normalizeTagName(name)
+			if err != nil {
+				return filter, fmt.Errorf("Invalid tags parameter: %s", err)
+			}
+			filter.Tags = append(filter.Tags, name)
+		}
+	}
+
+	switch c.DefaultQuery("tag_mode", "any") {
+	case "any":
+	case "all":
+		filter.AllTags = true
+	default:
+		return filter, errors.New("Invalid tag_mode parameter: must be any or all")
+	}
+
+	if value := c.Query("done"); value != "" {
+		done, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("Invalid done parameter: must be true or false")
+		}
+		filter.Done = &done
+	}
+
+	return filter, nil
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
@@ -1,30 +1,35 @@
 package handlers_test
 
 import (
-	"go-todo-api/internal/db"
+	"encoding/json"
 	"go-todo-api/internal/handlers"
 	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
 	"go-todo-api/internal/testutils"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestGetTasks(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
-	db.DB.Create(&models.Task{Title: "test", UserID: 1})
+	database.Create(&models.Task{Title: "test", UserID: 1})
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.GET("/tasks", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.GetTasks(c)
+		h.GetTasks(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
@@ -37,13 +42,14 @@ func TestGetTasks(t *testing.T) {
 }
 
 func TestGetTasksErrorNoUserID(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.GET("/tasks", func(c *gin.Context) {
-		handlers.GetTasks(c)
+		h.GetTasks(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodGet, "/tasks", nil)
@@ -54,14 +60,15 @@ func TestGetTasksErrorNoUserID(t *testing.T) {
 }
 
 func TestCreateTaskSuccess(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.POST("/tasks", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.CreateTask(c)
+		h.CreateTask(c)
 	})
 
 	body := `{"title":"Nova Tarefa","description":"Descrição da tarefa"}`
@@ -76,11 +83,12 @@ func TestCreateTaskSuccess(t *testing.T) {
 }
 
 func TestCreateTaskUnauthorized(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	r := gin.Default()
 	r.POST("/tasks", func(c *gin.Context) {
-		handlers.CreateTask(c)
+		h.CreateTask(c)
 	})
 
 	body := `{"title": "Nova tarefa", "description": "desc"}`
@@ -95,14 +103,15 @@ func TestCreateTaskUnauthorized(t *testing.T) {
 }
 
 func TestCreateTaskInvalidJSON(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.POST("/tasks", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.CreateTask(c)
+		h.CreateTask(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodPost, "/tasks", strings.NewReader("invalid json"))
@@ -116,16 +125,17 @@ func TestCreateTaskInvalidJSON(t *testing.T) {
 }
 
 func TestCreateTaskInternalServerError(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
-	db.DB.Migrator().DropTable(&models.Task{})
+	database.Migrator().DropTable(&models.Task{})
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.POST("/tasks", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.CreateTask(c)
+		h.CreateTask(c)
 	})
 
 	body := `{"title":"Falha","description":"Deve falhar"}`
@@ -140,15 +150,16 @@ func TestCreateTaskInternalServerError(t *testing.T) {
 }
 
 func TestUpdateTaskSuccess(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
-	db.DB.Create(&models.Task{Title: "Antiga", UserID: 1})
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Antiga", UserID: 1})
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.PUT("/tasks/:id", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.UpdateTask(c)
+		h.UpdateTask(c)
 	})
 
 	body := `{"title":"Updated","description":"New description","done":true}`
@@ -163,11 +174,12 @@ func TestUpdateTaskSuccess(t *testing.T) {
 }
 
 func TestUpdateTaskUnauthorized(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	r := gin.Default()
 	r.PUT("/tasks/:id", func(c *gin.Context) {
-		handlers.UpdateTask(c)
+		h.UpdateTask(c)
 	})
 
 	body := `{"title": "ed", "description": "desc", "done": true}`
@@ -182,14 +194,15 @@ func TestUpdateTaskUnauthorized(t *testing.T) {
 }
 
 func TestUpdateTaskNotFound(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.PUT("/tasks/:id", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.UpdateTask(c)
+		h.UpdateTask(c)
 	})
 
 	body := `{"title":"test","description":"nothing"}`
@@ -204,15 +217,16 @@ func TestUpdateTaskNotFound(t *testing.T) {
 }
 
 func TestUpdateTaskInvalidJSON(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
-	db.DB.Create(&models.Task{Title: "Teste", UserID: 1})
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Teste", UserID: 1})
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.PUT("/tasks/:id", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.UpdateTask(c)
+		h.UpdateTask(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodPut, "/tasks/1", strings.NewReader("invalid json"))
@@ -226,17 +240,18 @@ func TestUpdateTaskInvalidJSON(t *testing.T) {
 }
 
 func TestUpdateTaskInternalServerError(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
-	db.DB.Create(&models.Task{Title: "Error", UserID: 1})
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Error", UserID: 1})
 
-	db.DB.Migrator().DropTable(&models.Task{})
+	database.Migrator().DropTable(&models.Task{})
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.PUT("/tasks/:id", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.UpdateTask(c)
+		h.UpdateTask(c)
 	})
 
 	body := `{"title":"new","description":"desc","done":true}`
@@ -250,15 +265,16 @@ func TestUpdateTaskInternalServerError(t *testing.T) {
 }
 
 func TestDeleteTaskSuccess(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
-	db.DB.Create(&models.Task{Title: "Delete", UserID: 1})
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Delete", UserID: 1})
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.DELETE("/tasks/:id", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.DeleteTask(c)
+		h.DeleteTask(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodDelete, "/tasks/1", nil)
@@ -270,11 +286,12 @@ func TestDeleteTaskSuccess(t *testing.T) {
 }
 
 func TestDeleteTaskUnauthorized(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	r := gin.Default()
 	r.DELETE("/tasks/:id", func(c *gin.Context) {
-		handlers.DeleteTask(c)
+		h.DeleteTask(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodDelete, "/tasks/1", nil)
@@ -287,14 +304,15 @@ func TestDeleteTaskUnauthorized(t *testing.T) {
 }
 
 func TestDeleteTaskNotFound(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.DELETE("/tasks/:id", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.DeleteTask(c)
+		h.DeleteTask(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodDelete, "/tasks/999", nil)
@@ -306,17 +324,18 @@ func TestDeleteTaskNotFound(t *testing.T) {
 }
 
 func TestDeleteTaskInternalServerError(t *testing.T) {
-	db.DB = testutils.SetupTestDB(t)
-	db.DB.Create(&models.Task{Title: "Falha", UserID: 1})
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Falha", UserID: 1})
 
-	db.DB.Migrator().DropTable(&models.Task{})
+	database.Migrator().DropTable(&models.Task{})
 
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 
 	r.DELETE("/tasks/:id", func(c *gin.Context) {
 		c.Set("userID", uint(1))
-		handlers.DeleteTask(c)
+		h.DeleteTask(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodDelete, "/tasks/1", nil)
@@ -325,3 +344,700 @@ func TestDeleteTaskInternalServerError(t *testing.T) {
 
 	assert.True(t, w.Code == http.StatusInternalServerError || w.Code == http.StatusNotFound)
 }
+
+func TestCreateTaskStartAfterDue(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.POST("/tasks", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.CreateTask(c)
+	})
+
+	body := `{"title":"Dates","start_at":"2030-01-02T10:00:00Z","due_at":"2030-01-01T10:00:00Z"}`
+	req, _ := http.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "start_at must not be after due_at")
+}
+
+func TestCreateTaskDueDateStoredInUTC(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.POST("/tasks", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.CreateTask(c)
+	})
+
+	body := `{"title":"Dates","due_at":"2030-01-01T10:00:00-03:00"}`
+	req, _ := http.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"due_at":"2030-01-01T13:00:00Z"`)
+}
+
+func TestGetTasksDueDateFilters(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	past := time.Now().UTC().Add(-48 * time.Hour)
+	future := time.Now().UTC().Add(48 * time.Hour)
+	database.Create(&models.Task{Title: "late", DueAt: &past, Position: "1", UserID: 1})
+	database.Create(&models.Task{Title: "upcoming", DueAt: &future, Position: "2", UserID: 1})
+	database.Create(&models.Task{Title: "finished", DueAt: &past, Done: true, Position: "3", UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTasks(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks?overdue=true", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "late")
+	assert.NotContains(t, w.Body.String(), "upcoming")
+	assert.NotContains(t, w.Body.String(), "finished")
+
+	req, _ = http.NewRequest(http.MethodGet, "/tasks?due_after="+time.Now().UTC().Format(time.RFC3339), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "upcoming")
+	assert.NotContains(t, w.Body.String(), "late")
+
+	req, _ = http.NewRequest(http.MethodGet, "/tasks?due_before=yesterday", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid due_before parameter")
+}
+
+func TestGetOverdueTasks(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	past := time.Now().UTC().Add(-time.Hour)
+	database.Create(&models.Task{Title: "mine", DueAt: &past, UserID: 1})
+	database.Create(&models.Task{Title: "other", DueAt: &past, UserID: 2})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks/overdue", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetOverdueTasks(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/overdue", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "mine")
+	assert.NotContains(t, w.Body.String(), "other")
+}
+
+func TestGetTasksFilterAndSort(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	database.Create(&models.Task{Title: "Buy milk", Position: "1", UserID: 1})
+	database.Create(&models.Task{Title: "Write report", Done: true, Position: "2", UserID: 1})
+	database.Create(&models.Task{Title: "Buy bread", Position: "3", UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTasks(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks?done=false&title=buy&sort=title&order=asc", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var page struct {
+		Data  []models.Task `json:"data"`
+		Total int64         `json:"total"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, int64(2), page.Total)
+	if assert.Len(t, page.Data, 2) {
+		assert.Equal(t, "Buy bread", page.Data[0].Title)
+		assert.Equal(t, "Buy milk", page.Data[1].Title)
+	}
+}
+
+func TestGetTasksCursorPagination(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	for i, title := range []string{"d", "b", "e", "a", "c"} {
+		database.Create(&models.Task{Title: title, Position: strconv.Itoa(i + 1), UserID: 1})
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTasks(c)
+	})
+
+	var titles []string
+	url := "/tasks?sort=title&order=desc&limit=2"
+	for i := 0; i < 5 && url != ""; i++ {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var page struct {
+			Data       []models.Task `json:"data"`
+			Total      int64         `json:"total"`
+			NextCursor *string       `json:"next_cursor"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, int64(5), page.Total)
+
+		for _, task := range page.Data {
+			titles = append(titles, task.Title)
+		}
+
+		url = ""
+		if page.NextCursor != nil {
+			url = "/tasks?sort=title&order=desc&limit=2&cursor=" + *page.NextCursor
+		}
+	}
+
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, titles)
+}
+
+func TestGetTasksOffsetPagination(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	for i, title := range []string{"first", "second", "third"} {
+		database.Create(&models.Task{Title: title, Position: strconv.Itoa(i + 1), UserID: 1})
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTasks(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks?limit=1&offset=1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "second")
+	assert.NotContains(t, w.Body.String(), "first")
+	assert.NotContains(t, w.Body.String(), "third")
+}
+
+func TestGetTasksInvalidParams(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTasks(c)
+	})
+
+	cases := map[string]string{
+		"/tasks?sort=password":       "Invalid sort parameter",
+		"/tasks?order=sideways":      "Invalid order parameter",
+		"/tasks?limit=0":             "Invalid limit parameter",
+		"/tasks?offset=-1":           "Invalid offset parameter",
+		"/tasks?done=maybe":          "Invalid done parameter",
+		"/tasks?updated_since=soon":  "Invalid updated_since parameter",
+		"/tasks?cursor=not-a-cursor": "Invalid cursor parameter",
+		"/tasks?cursor=abc&offset=1": "Cursor and offset cannot be combined",
+	}
+
+	for url, message := range cases {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+		assert.Contains(t, w.Body.String(), message, url)
+	}
+}
+
+func TestGetTaskSuccess(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Single", UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTask(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Single")
+	assert.Equal(t, `"1-1"`, w.Header().Get("ETag"))
+}
+
+func TestGetTaskNotModified(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Cached", UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTask(c)
+	})
+	r.PUT("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.UpdateTask(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+
+	req, _ = http.NewRequest(http.MethodGet, "/tasks/1", nil)
+	req.Header.Set("If-None-Match", "W/"+etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	body := `{"title":"Changed"}`
+	req, _ = http.NewRequest(http.MethodPut, "/tasks/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest(http.MethodGet, "/tasks/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Changed")
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
+func TestGetTaskOtherUser(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Private", UserID: 2})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.GET("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.GetTask(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "Task not found")
+}
+
+func TestGetTaskUnauthorized(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+
+	r := gin.Default()
+	r.GET("/tasks/:id", func(c *gin.Context) {
+		h.GetTask(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tasks/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestPatchTaskMergePatch(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Keep me", Description: "desc", UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.PATCH("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.PatchTask(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(`{"done":true,"description":null}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var task models.Task
+	database.First(&task, 1)
+	assert.Equal(t, "Keep me", task.Title)
+	assert.Equal(t, "", task.Description)
+	assert.True(t, task.Done)
+	assert.Equal(t, uint(2), task.Version)
+}
+
+func TestPatchTaskJSONPatch(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Original", UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.PATCH("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.PatchTask(c)
+	})
+
+	body := `[{"op":"test","path":"/title","value":"Original"},{"op":"replace","path":"/title","value":"Patched"}]`
+	req, _ := http.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Patched")
+
+	req, _ = http.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestPatchTaskJSONPatchClearsField(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	dueAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	database.Create(&models.Task{Title: "Dated", DueAt: &dueAt, UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.PATCH("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.PatchTask(c)
+	})
+
+	body := `[{"op":"replace","path":"/due_at","value":null}]`
+	req, _ := http.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json-patch+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var task models.Task
+	database.First(&task, 1)
+	assert.Nil(t, task.DueAt)
+}
+
+func TestPatchTaskValidation(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Valid", UserID: 1})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.PATCH("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.PatchTask(c)
+	})
+
+	cases := []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{"application/merge-patch+json", `{"title":""}`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"start_at":"2030-01-02T00:00:00Z","due_at":"2030-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"done":"yes"}`, http.StatusBadRequest},
+		{"application/json-patch+json", `[{"op":"remove","path":"/missing"}]`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"title":"Changed","version":7}`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"title":"Changed","position":"0"}`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"title":"Changed","created_at":"2000-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"title":"Changed","id":null}`, http.StatusBadRequest},
+		{"application/json-patch+json", `[{"op":"replace","path":"/title","value":"Changed"},{"op":"replace","path":"/comment_count","value":3}]`, http.StatusBadRequest},
+		{"application/json-patch+json", `[{"op":"replace","path":"/title","value":"Changed"},{"op":"remove","path":"/checklist"}]`, http.StatusBadRequest},
+		{"application/json-patch+json", `[{"op":"replace","path":"/deleted_at","value":"2030-01-01T00:00:00Z"}]`, http.StatusBadRequest},
+		{"application/json-patch+json", `[{"op":"replace","path":"/deleted_at","value":1e400}]`, http.StatusBadRequest},
+		{"text/plain", `done`, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.body)
+	}
+
+	var task models.Task
+	database.First(&task, 1)
+	assert.Equal(t, "Valid", task.Title)
+}
+
+func TestPatchTaskNotFound(t *testing.T) {
+	database := testutils.SetupTestDB(t)
+	h := handlers.NewTaskHandler(store.NewGormStores(database, 0))
+	database.Create(&models.Task{Title: "Other", UserID: 2})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+
+	r.PATCH("/tasks/:id", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		h.PatchTask(c)
+	})
+
+	req, _ := http.NewRequest(http.MethodPatch, "/tasks/1", strings.NewReader(`{"done":true}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func setupSubtaskRouter(t *testing.T) *gin.Engine {
+	h := handlers.NewTaskHandler(store.NewGormStores(testutils.SetupTestDB(t), 0))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.GET("/tasks", h.GetTasks)
+	r.POST("/tasks", h.CreateTask)
+	r.GET("/tasks/:id", h.GetTask)
+	r.GET("/tasks/:id/subtree", h.GetSubtree)
+	r.POST("/tasks/:id/move", h.MoveTask)
+	r.PUT("/tasks/:id", h.UpdateTask)
+	r.PATCH("/tasks/:id", h.PatchTask)
+	r.DELETE("/tasks/:id", h.DeleteTask)
+
+	return r
+}
+
+func TestSubtasks(t *testing.T) {
+	r := setupSubtaskRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Release","auto_complete":true}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Write notes","parent_id":1}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Tag build","parent_id":1}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Push tag","parent_id":3}`)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Orphan","parent_id":99}`).Code)
+
+	w := serve(r, http.MethodGet, "/tasks/1/subtree", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tree struct {
+		Title    string `json:"title"`
+		Children []struct {
+			Title    string `json:"title"`
+			Children []struct {
+				Title string `json:"title"`
+			} `json:"children"`
+		} `json:"children"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &tree)
+	assert.Equal(t, "Release", tree.Title)
+	assert.Len(t, tree.Children, 2)
+	assert.Equal(t, "Push tag", tree.Children[1].Children[0].Title)
+
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/99/subtree", "").Code)
+
+	// Moving a task below its own descendant would create a cycle.
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPatch, "/tasks/1", `{"parent_id":4}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPatch, "/tasks/3", `{"parent_id":3}`).Code)
+
+	serve(r, http.MethodPatch, "/tasks/2", `{"done":true}`)
+	serve(r, http.MethodPatch, "/tasks/3", `{"done":true}`)
+
+	var task models.Task
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks/1", "").Body.Bytes(), &task)
+	assert.True(t, task.Done)
+	assert.Equal(t, models.SubtaskProgress{Done: 2, Total: 2}, task.Subtasks)
+
+	var page struct {
+		Data []models.Task `json:"data"`
+	}
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks?parent_id=root", "").Body.Bytes(), &page)
+	assert.Len(t, page.Data, 1)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodGet, "/tasks?parent_id=top", "").Code)
+
+	// Recurring tasks are completed by hand.
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPatch, "/tasks/1", `{"rrule":"FREQ=WEEKLY","due_at":"2025-01-06T09:00:00Z"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Weekly","auto_complete":true,"rrule":"FREQ=WEEKLY","due_at":"2025-01-06T09:00:00Z"}`).Code)
+}
+
+func TestDeleteTaskSubtasks(t *testing.T) {
+	r := setupSubtaskRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Parent"}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Child","parent_id":1}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Grandchild","parent_id":2}`)
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodDelete, "/tasks/2?subtasks=orphan", "").Code)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/tasks/2", "").Code)
+
+	var task models.Task
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks/3", "").Body.Bytes(), &task)
+	assert.Equal(t, uint(1), *task.ParentID)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/tasks/1?subtasks=delete", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/3", "").Code)
+}
+
+func TestTaskPriority(t *testing.T) {
+	r := setupSubtaskRouter(t)
+
+	var task models.Task
+	json.Unmarshal(serve(r, http.MethodPost, "/tasks", `{"title":"Someday"}`).Body.Bytes(), &task)
+	assert.Equal(t, 4, task.Priority)
+
+	assert.Equal(t, http.StatusCreated, serve(r, http.MethodPost, "/tasks", `{"title":"Urgent","priority":1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks", `{"title":"Bad","priority":5}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPatch, "/tasks/1", `{"priority":-1}`).Code)
+
+	var page struct {
+		Data []models.Task `json:"data"`
+	}
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks?sort=priority", "").Body.Bytes(), &page)
+	if assert.Len(t, page.Data, 2) {
+		assert.Equal(t, "Urgent", page.Data[0].Title)
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	r := setupSubtaskRouter(t)
+
+	for _, title := range []string{"One", "Two", "Three"} {
+		serve(r, http.MethodPost, "/tasks", `{"title":"`+title+`"}`)
+	}
+
+	titles := func() []string {
+		var page struct {
+			Data []models.Task `json:"data"`
+		}
+		json.Unmarshal(serve(r, http.MethodGet, "/tasks", "").Body.Bytes(), &page)
+		var result []string
+		for _, task := range page.Data {
+			result = append(result, task.Title)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"One", "Two", "Three"}, titles())
+
+	w := serve(r, http.MethodPost, "/tasks/3/move", `{"before":1}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3-2"`, w.Header().Get("ETag"))
+	assert.Equal(t, []string{"Three", "One", "Two"}, titles())
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodPost, "/tasks/3/move", `{"after":2}`).Code)
+	assert.Equal(t, []string{"One", "Two", "Three"}, titles())
+
+	// Positions are assigned by the server.
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPatch, "/tasks/1", `{"position":"zzz"}`).Code)
+	assert.Equal(t, []string{"One", "Two", "Three"}, titles())
+
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/move", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/move", `{"before":2,"after":3}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/move", `{"before":1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(r, http.MethodPost, "/tasks/1/move", `{"before":99}`).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPost, "/tasks/99/move", `{"before":1}`).Code)
+}
+
+func TestTaskTimestamps(t *testing.T) {
+	r := setupSubtaskRouter(t)
+
+	var task models.Task
+	w := serve(r, http.MethodPost, "/tasks", `{"title":"Ship","created_at":"2000-01-01T00:00:00Z","completed_at":"2000-01-01T00:00:00Z"}`)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.True(t, task.CreatedAt.After(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Nil(t, task.CompletedAt)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Wait"}`)
+
+	since := time.Now().UTC()
+	time.Sleep(5 * time.Millisecond)
+
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"done":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	if assert.NotNil(t, task.CompletedAt) {
+		assert.True(t, task.CompletedAt.After(since))
+	}
+	assert.True(t, task.UpdatedAt.After(since))
+
+	var page struct {
+		Data []models.Task `json:"data"`
+	}
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks?updated_since="+url.QueryEscape(since.Format(time.RFC3339Nano)), "").Body.Bytes(), &page)
+	if assert.Len(t, page.Data, 1) {
+		assert.Equal(t, "Ship", page.Data[0].Title)
+	}
+
+	w = serve(r, http.MethodPatch, "/tasks/1", `{"done":false}`)
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Nil(t, task.CompletedAt)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// findTrashedTask loads the trashed task named by the :id parameter for the
+// current user. It writes the error response and returns false if there is
+// none.
+func (h *TaskHandler) findTrashedTask(c *gin.Context, userID uint) (models.Task, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+		return models.Task{}, false
+	}
+
+	task, err := h.tasks.GetTrashed(c.Request.Context(), userID, uint(id))
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found in trash"})
+		return task, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching task"})
+		return task, false
+	}
+
+	return task, true
+}
+
+// GetTrash lists the user's deleted tasks, most recently deleted first.
+func (h *TaskHandler) GetTrash(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tasks, err := h.tasks.Trash(c.Request.Context(), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// RestoreTask takes a task out of the trash together with the subtasks
+// deleted along with it.
+func (h *TaskHandler) RestoreTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.findTrashedTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.tasks.Restore(changeContext(c), &task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error restoring task"})
+		return
+	}
+
+	task, err := h.tasks.Get(c.Request.Context(), task.UserID, task.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching task"})
+		return
+	}
+
+	c.Header("ETag", taskETag(task))
+	c.JSON(http.StatusOK, task)
+}
+
+// PurgeTask permanently deletes a task from the trash, together with the
+// subtasks deleted along with it.
+func (h *TaskHandler) PurgeTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	task, ok := h.findTrashedTask(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	if err := h.tasks.Purge(changeContext(c), &task); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting task"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Task permanently deleted"})
+}
@@ -0,0 +1,83 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"go-todo-api/internal/handlers"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupTrashRouter serves the task and trash routes for user 1.
+func setupTrashRouter(t *testing.T) *gin.Engine {
+	h := handlers.NewTaskHandler(store.NewGormStores(testutils.SetupTestDB(t), 0))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", uint(1))
+	})
+
+	r.GET("/tasks", h.GetTasks)
+	r.POST("/tasks", h.CreateTask)
+	r.GET("/tasks/:id", h.GetTask)
+	r.PATCH("/tasks/:id", h.PatchTask)
+	r.DELETE("/tasks/:id", h.DeleteTask)
+	r.POST("/tasks/:id/restore", h.RestoreTask)
+	r.GET("/trash", h.GetTrash)
+	r.DELETE("/trash/:id", h.PurgeTask)
+
+	return r
+}
+
+func TestTrash(t *testing.T) {
+	r := setupTrashRouter(t)
+
+	serve(r, http.MethodPost, "/tasks", `{"title":"Plan trip"}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Book hotel","parent_id":1}`)
+	serve(r, http.MethodPost, "/tasks", `{"title":"Keep"}`)
+
+	// Deleted tasks can't be created or patched into the trash.
+	serve(r, http.MethodPatch, "/tasks/3", `{"deleted_at":"2030-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodGet, "/tasks/3", "").Code)
+
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/tasks/1?subtasks=delete", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodGet, "/tasks/1", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPost, "/tasks/3/restore", "").Code)
+
+	var trash []models.Task
+	w := serve(r, http.MethodGet, "/trash", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &trash)
+	assert.Len(t, trash, 2)
+	assert.True(t, trash[0].DeletedAt.Valid)
+
+	w = serve(r, http.MethodPost, "/tasks/1/restore", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var task models.Task
+	json.Unmarshal(w.Body.Bytes(), &task)
+	assert.Equal(t, "Plan trip", task.Title)
+	assert.False(t, task.DeletedAt.Valid)
+	assert.Equal(t, models.SubtaskProgress{Done: 0, Total: 1}, task.Subtasks)
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+
+	var page struct {
+		Data []models.Task `json:"data"`
+	}
+	json.Unmarshal(serve(r, http.MethodGet, "/tasks", "").Body.Bytes(), &page)
+	assert.Len(t, page.Data, 3)
+
+	serve(r, http.MethodDelete, "/tasks/3", "")
+	assert.Equal(t, http.StatusOK, serve(r, http.MethodDelete, "/trash/3", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodDelete, "/trash/3", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, http.MethodPost, "/tasks/3/restore", "").Code)
+
+	json.Unmarshal(serve(r, http.MethodGet, "/trash", "").Body.Bytes(), &trash)
+	assert.Empty(t, trash)
+}
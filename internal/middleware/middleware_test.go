@@ -3,9 +3,10 @@ package middleware
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
-	"go-todo-api/internal/utils"
+	"go-todo-api/internal/testutils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -15,7 +16,8 @@ func TestAuthMiddleware(t *testing.T) {
 	r := gin.New()
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
-	r.Use(JWTAuthMiddleware())
+	tokens := testutils.SetupTokenManager(t)
+	r.Use(JWTAuthMiddleware(tokens))
 	r.GET("/protected", func(c *gin.Context) {
 		userID, exists := c.Get("userID")
 		if !exists {
@@ -26,7 +28,7 @@ func TestAuthMiddleware(t *testing.T) {
 	})
 
 	// Generate valid token
-	token, err := utils.GenerateAccessToken(1)
+	token, err := tokens.GenerateAccessToken(1)
 	assert.NoError(t, err)
 
 	// Request with valid token
@@ -59,3 +61,55 @@ func TestAuthMiddleware(t *testing.T) {
 	r.ServeHTTP(wNoBearer, reqNoBearer)
 	assert.Equal(t, http.StatusUnauthorized, wNoBearer.Code)
 }
+
+func TestAuthMiddlewareRejectsRefreshToken(t *testing.T) {
+	r := gin.New()
+	tokens := testutils.SetupTokenManager(t)
+	r.Use(JWTAuthMiddleware(tokens))
+	r.GET("/protected", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	token, err := tokens.GenerateRefreshToken(1)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequestID(t *testing.T) {
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("requestID"))
+	})
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("")
+	assert.Len(t, w.Body.String(), 32)
+	assert.Equal(t, w.Body.String(), w.Header().Get(RequestIDHeader))
+	assert.NotEqual(t, w.Body.String(), serve("").Body.String())
+
+	w = serve("req-42_a.b")
+	assert.Equal(t, "req-42_a.b", w.Body.String())
+	assert.Equal(t, "req-42_a.b", w.Header().Get(RequestIDHeader))
+
+	for _, id := range []string{"bad id", "<script>", strings.Repeat("a", 129)} {
+		w = serve(id)
+		assert.NotEqual(t, id, w.Body.String())
+		assert.Len(t, w.Body.String(), 32)
+	}
+}
@@ -0,0 +1,53 @@
+package migrate
+
+import "fmt"
+
+// migrationLockKey identifies the Postgres advisory lock held while
+// migrating. It is an arbitrary constant shared by every replica.
+const migrationLockKey = 7_361_254_819
+
+type dialect struct {
+	createTable string
+	// tableExists counts the schema_migrations tables visible to the
+	// connection, without creating one.
+	tableExists   string
+	countVersion  string
+	insertVersion string
+	deleteVersion string
+	// begin starts a transaction on the migration connection.
+	begin string
+	// lock and unlock serialize migrations across processes. SQLite has no
+	// advisory locks; there "BEGIN IMMEDIATE" takes the database write lock
+	// for the duration of each migration instead.
+	lock   string
+	unlock string
+}
+
+var dialects = map[string]dialect{
+	"postgres": {
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)`,
+		tableExists:   "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'",
+		countVersion:  "SELECT COUNT(*) FROM schema_migrations WHERE version = $1",
+		insertVersion: "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		deleteVersion: "DELETE FROM schema_migrations WHERE version = $1",
+		begin:         "BEGIN",
+		lock:          fmt.Sprintf("SELECT pg_advisory_lock(%d)", migrationLockKey),
+		unlock:        fmt.Sprintf("SELECT pg_advisory_unlock(%d)", migrationLockKey),
+	},
+	"sqlite": {
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at DATETIME NOT NULL
+		)`,
+		tableExists:   "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'",
+		countVersion:  "SELECT COUNT(*) FROM schema_migrations WHERE version = ?",
+		insertVersion: "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		deleteVersion: "DELETE FROM schema_migrations WHERE version = ?",
+		begin:         "BEGIN IMMEDIATE",
+	},
+}
@@ -0,0 +1,89 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T) *sql.DB {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return sqlDB
+}
+
+func hasTable(t *testing.T, db *sql.DB, name string) bool {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	assert.NoError(t, err)
+	return count > 0
+}
+
+func TestDialectsHaveTheSameMigrations(t *testing.T) {
+	postgres, err := load("postgres")
+	assert.NoError(t, err)
+	sqlite, err := load("sqlite")
+	assert.NoError(t, err)
+
+	assert.Equal(t, len(postgres), len(sqlite))
+	for i := range postgres {
+		assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+		assert.NotEmpty(t, postgres[i].Down, "%d_%s", postgres[i].Version, postgres[i].Name)
+		assert.NotEmpty(t, sqlite[i].Down, "%d_%s", sqlite[i].Version, sqlite[i].Name)
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+
+	m, err := New(db, "sqlite")
+	assert.NoError(t, err)
+
+	pending, err := m.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(m.Migrations()))
+	assert.False(t, hasTable(t, db, "schema_migrations"), "Pending must not create tables")
+
+	applied, err := m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(m.Migrations()))
+	assert.True(t, hasTable(t, db, "tasks"))
+
+	applied, err = m.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := m.Status(ctx)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	for range m.Migrations() {
+		_, err := m.Down(ctx)
+		assert.NoError(t, err)
+	}
+	assert.False(t, hasTable(t, db, "tasks"))
+
+	_, err = m.Down(ctx)
+	assert.ErrorIs(t, err, ErrNoMigrations)
+}
+
+func TestUnknownDialect(t *testing.T) {
+	_, err := New(nil, "mysql")
+	assert.ErrorContains(t, err, "unsupported migration dialect")
+}
@@ -0,0 +1,12 @@
+CREATE TABLE projects (
+    id       BIGSERIAL PRIMARY KEY,
+    name     TEXT NOT NULL,
+    color    TEXT NOT NULL DEFAULT '',
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    position BIGINT NOT NULL DEFAULT 0,
+    user_id  BIGINT NOT NULL
+);
+CREATE INDEX idx_projects_user_id ON projects (user_id);
+
+ALTER TABLE tasks ADD COLUMN project_id BIGINT;
+CREATE INDEX idx_tasks_project_id ON tasks (project_id);
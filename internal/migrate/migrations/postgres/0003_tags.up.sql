@@ -0,0 +1,13 @@
+CREATE TABLE tags (
+    id      BIGSERIAL PRIMARY KEY,
+    name    TEXT NOT NULL,
+    user_id BIGINT NOT NULL
+);
+CREATE UNIQUE INDEX idx_tags_user_id_name ON tags (user_id, name);
+
+CREATE TABLE task_tags (
+    task_id BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    tag_id  BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, tag_id)
+);
+CREATE INDEX idx_task_tags_tag_id ON task_tags (tag_id);
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN parent_id BIGINT;
+ALTER TABLE tasks ADD COLUMN auto_complete BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX idx_tasks_parent_id ON tasks (parent_id);
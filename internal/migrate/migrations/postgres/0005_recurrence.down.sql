@@ -0,0 +1,4 @@
+DROP TABLE task_occurrences;
+ALTER TABLE tasks DROP COLUMN rrule_tz;
+ALTER TABLE tasks DROP COLUMN rrule_start;
+ALTER TABLE tasks DROP COLUMN rrule;
@@ -0,0 +1,12 @@
+ALTER TABLE tasks ADD COLUMN rrule TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN rrule_start TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN rrule_tz TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE task_occurrences (
+    id           BIGSERIAL PRIMARY KEY,
+    task_id      BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    start_at     TIMESTAMPTZ,
+    due_at       TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_task_occurrences_task_id ON task_occurrences (task_id);
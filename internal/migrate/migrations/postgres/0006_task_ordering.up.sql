@@ -0,0 +1,9 @@
+ALTER TABLE tasks ADD COLUMN priority SMALLINT NOT NULL DEFAULT 4;
+-- Ranking keys compare byte-wise.
+ALTER TABLE tasks ADD COLUMN position TEXT COLLATE "C" NOT NULL DEFAULT '';
+
+-- Number existing tasks in id order with keys that end in a non-zero digit.
+UPDATE tasks SET position = LPAD(id::TEXT, 10, '0') || '1';
+
+-- Tasks keep distinct positions, so moves always find room between them.
+CREATE UNIQUE INDEX idx_tasks_user_id_position ON tasks (user_id, position);
@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE tasks ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE tasks ADD COLUMN completed_at TIMESTAMPTZ;
+CREATE INDEX idx_tasks_user_id_updated_at ON tasks (user_id, updated_at);
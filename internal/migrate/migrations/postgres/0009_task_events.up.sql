@@ -0,0 +1,13 @@
+-- Events outlive their task, so task_id has no foreign key and the
+-- history still shows a task's deletion after it has been purged.
+CREATE TABLE task_events (
+    id         BIGSERIAL PRIMARY KEY,
+    task_id    BIGINT NOT NULL,
+    user_id    BIGINT NOT NULL,
+    action     TEXT NOT NULL,
+    changes    TEXT NOT NULL DEFAULT '{}',
+    actor_id   BIGINT NOT NULL,
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_task_events_task_id ON task_events (task_id);
@@ -0,0 +1,16 @@
+CREATE TABLE attachments (
+    id           BIGSERIAL PRIMARY KEY,
+    task_id      BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    uploader_id  BIGINT NOT NULL,
+    filename     TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size         BIGINT NOT NULL,
+    blob_key     TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_attachments_task_id ON attachments (task_id);
+
+CREATE TABLE orphaned_blobs (
+    blob_key   TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE checklist_items;
+ALTER TABLE tasks DROP COLUMN checklist_auto_complete;
@@ -0,0 +1,12 @@
+ALTER TABLE tasks ADD COLUMN checklist_auto_complete BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE checklist_items (
+    id         BIGSERIAL PRIMARY KEY,
+    task_id    BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    text       TEXT NOT NULL,
+    checked    BOOLEAN NOT NULL DEFAULT FALSE,
+    position   INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_checklist_items_task_id ON checklist_items (task_id);
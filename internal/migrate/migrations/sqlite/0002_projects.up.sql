@@ -0,0 +1,12 @@
+CREATE TABLE projects (
+    id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    name     TEXT NOT NULL,
+    color    TEXT NOT NULL DEFAULT '',
+    archived NUMERIC NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0,
+    user_id  INTEGER NOT NULL
+);
+CREATE INDEX idx_projects_user_id ON projects (user_id);
+
+ALTER TABLE tasks ADD COLUMN project_id INTEGER;
+CREATE INDEX idx_tasks_project_id ON tasks (project_id);
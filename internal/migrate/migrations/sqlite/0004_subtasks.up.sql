@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN parent_id INTEGER;
+ALTER TABLE tasks ADD COLUMN auto_complete NUMERIC NOT NULL DEFAULT 0;
+CREATE INDEX idx_tasks_parent_id ON tasks (parent_id);
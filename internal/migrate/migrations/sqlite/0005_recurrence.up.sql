@@ -0,0 +1,12 @@
+ALTER TABLE tasks ADD COLUMN rrule TEXT NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN rrule_start DATETIME;
+ALTER TABLE tasks ADD COLUMN rrule_tz TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE task_occurrences (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id      INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    start_at     DATETIME,
+    due_at       DATETIME,
+    completed_at DATETIME NOT NULL
+);
+CREATE INDEX idx_task_occurrences_task_id ON task_occurrences (task_id);
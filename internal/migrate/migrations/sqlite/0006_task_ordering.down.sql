@@ -0,0 +1,3 @@
+DROP INDEX idx_tasks_user_id_position;
+ALTER TABLE tasks DROP COLUMN position;
+ALTER TABLE tasks DROP COLUMN priority;
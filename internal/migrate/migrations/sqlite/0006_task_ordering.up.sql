@@ -0,0 +1,8 @@
+ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 4;
+ALTER TABLE tasks ADD COLUMN position TEXT NOT NULL DEFAULT '';
+
+-- Number existing tasks in id order with keys that end in a non-zero digit.
+UPDATE tasks SET position = printf('%010d', id) || '1';
+
+-- Tasks keep distinct positions, so moves always find room between them.
+CREATE UNIQUE INDEX idx_tasks_user_id_position ON tasks (user_id, position);
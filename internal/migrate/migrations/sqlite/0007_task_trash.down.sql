@@ -0,0 +1,4 @@
+-- Trashed tasks would reappear once the column is gone.
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+DROP INDEX idx_tasks_deleted_at;
+ALTER TABLE tasks DROP COLUMN deleted_at;
@@ -0,0 +1,4 @@
+DROP INDEX idx_tasks_user_id_updated_at;
+ALTER TABLE tasks DROP COLUMN completed_at;
+ALTER TABLE tasks DROP COLUMN updated_at;
+ALTER TABLE tasks DROP COLUMN created_at;
@@ -0,0 +1,11 @@
+-- SQLite only accepts constant defaults here, so existing tasks are stamped
+-- with the migration time afterwards.
+ALTER TABLE tasks ADD COLUMN created_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE tasks ADD COLUMN updated_at DATETIME NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE tasks ADD COLUMN completed_at DATETIME;
+
+UPDATE tasks SET
+    created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%S+00:00', 'now');
+
+CREATE INDEX idx_tasks_user_id_updated_at ON tasks (user_id, updated_at);
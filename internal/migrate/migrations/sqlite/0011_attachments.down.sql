@@ -0,0 +1,2 @@
+DROP TABLE orphaned_blobs;
+DROP TABLE attachments;
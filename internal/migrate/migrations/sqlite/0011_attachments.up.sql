@@ -0,0 +1,16 @@
+CREATE TABLE attachments (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id      INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    uploader_id  INTEGER NOT NULL,
+    filename     TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size         INTEGER NOT NULL,
+    blob_key     TEXT NOT NULL,
+    created_at   DATETIME NOT NULL
+);
+CREATE INDEX idx_attachments_task_id ON attachments (task_id);
+
+CREATE TABLE orphaned_blobs (
+    blob_key   TEXT PRIMARY KEY,
+    created_at DATETIME NOT NULL
+);
@@ -0,0 +1,12 @@
+ALTER TABLE tasks ADD COLUMN checklist_auto_complete NUMERIC NOT NULL DEFAULT 0;
+
+CREATE TABLE checklist_items (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id    INTEGER NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    text       TEXT NOT NULL,
+    checked    NUMERIC NOT NULL DEFAULT 0,
+    position   INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE INDEX idx_checklist_items_task_id ON checklist_items (task_id);
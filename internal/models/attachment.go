@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Attachment is a file uploaded to a task. The content lives in blob
+// storage under Key; the row only keeps what clients need to list and
+// download it.
+type Attachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TaskID      uint      `json:"task_id" gorm:"index"`
+	UploaderID  uint      `json:"uploader_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Key         string    `json:"-" gorm:"column:blob_key"`
+	CreatedAt   time.Time `json:"created_at"`
+}
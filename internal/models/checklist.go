@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ChecklistItem is a line of a task's checklist, for steps too small to be
+// subtasks.
+type ChecklistItem struct {
+	ID      uint   `json:"id" gorm:"primaryKey"`
+	TaskID  uint   `json:"task_id" gorm:"index"`
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+	// Position orders the items of a checklist, starting at 1.
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChecklistProgress counts a task's checklist items and how many are
+// checked.
+type ChecklistProgress struct {
+	Checked int64 `json:"checked"`
+	Total   int64 `json:"total"`
+}
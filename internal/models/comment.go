@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Comment is a note on a task. Its Markdown body is stored as written and
+// left to clients to render.
+type Comment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	TaskID    uint      `json:"task_id" gorm:"index"`
+	AuthorID  uint      `json:"author_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Project groups tasks. Tasks without a project are in the user's Inbox.
+type Project struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Archived bool   `json:"archived"`
+	Position int    `json:"position"`
+
+	UserID uint `json:"-" gorm:"index"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Tag labels tasks. Names are unique per user.
+type Tag struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	// TaskCount is the number of tasks carrying the tag. It is only filled
+	// in by tag listings.
+	TaskCount int64 `json:"task_count" gorm:"->"`
+
+	UserID uint `json:"-" gorm:"index"`
+}
@@ -1,10 +1,76 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type Task struct {
-	ID          uint   `json:"id" gorm:"primaryKey"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Done        bool   `json:"done"`
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Done        bool       `json:"done"`
+	StartAt     *time.Time `json:"start_at,omitempty" gorm:"index"`
+	DueAt       *time.Time `json:"due_at,omitempty" gorm:"index"`
+	Version     uint       `json:"version" gorm:"not null;default:1"`
+	// Priority ranges from 1 (highest) to 4, the default.
+	Priority int `json:"priority"`
+	// Position is the task's fractional ranking key in the user's manual
+	// order. It is changed by moving the task.
+	Position  string `json:"position"`
+	ProjectID *uint  `json:"project_id" gorm:"index"`
+	ParentID  *uint  `json:"parent_id" gorm:"index"`
+	// AutoComplete makes the task follow its subtasks: it is completed once
+	// they are all done and reopened when one of them is reopened.
+	AutoComplete bool `json:"auto_complete"`
+	// ChecklistAutoComplete makes the task follow its checklist in the
+	// same way.
+	ChecklistAutoComplete bool `json:"checklist_auto_complete"`
+	// RRule is an RFC 5545 recurrence rule. Completing a recurring task
+	// records the occurrence and moves the task to the next one.
+	RRule string `json:"rrule" gorm:"column:rrule;not null;default:''"`
+	// RRuleStart is the first occurrence of the current rule, so COUNT and
+	// UNTIL apply to the whole series.
+	RRuleStart *time.Time `json:"-" gorm:"column:rrule_start"`
+	// RRuleTZ is the IANA time zone the rule is expanded in, so BYDAY and
+	// BYHOUR follow local days and hours across DST changes. Empty is UTC.
+	RRuleTZ string `json:"rrule_tz" gorm:"column:rrule_tz;not null;default:''"`
+	// Subtasks summarises the task's direct subtasks. It is filled in by
+	// the stores.
+	Subtasks SubtaskProgress `json:"subtasks" gorm:"-"`
+	// Checklist summarises the task's checklist. It is filled in by the
+	// stores.
+	Checklist ChecklistProgress `json:"checklist" gorm:"-"`
+	// Tags holds the names of the task's tags, sorted. The stores keep
+	// them in the task_tags join table.
+	Tags []string `json:"tags" gorm:"-"`
+	// CommentCount is the number of comments on the task. It is filled in
+	// by the stores.
+	CommentCount int64 `json:"comment_count" gorm:"-"`
+	// CompletedAt is when the task was last completed. The stores set it
+	// when Done becomes true and clear it when the task is reopened.
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// DeletedAt is set while the task is in the trash. Trashed tasks are
+	// left out of every query unless asked for.
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	UserID uint `json:"-"`
 }
+
+// SubtaskProgress counts a task's direct subtasks and how many are done.
+type SubtaskProgress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// TaskOccurrence records a completed occurrence of a recurring task.
+type TaskOccurrence struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	TaskID      uint       `json:"task_id" gorm:"index"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	CompletedAt time.Time  `json:"completed_at"`
+}
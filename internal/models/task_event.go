@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Actions recorded in the task history.
+const (
+	TaskCreated  = "created"
+	TaskUpdated  = "updated"
+	TaskDeleted  = "deleted"
+	TaskRestored = "restored"
+	TaskPurged   = "purged"
+)
+
+// TaskEvent is an entry of a task's history. Events are only ever added:
+// they are kept after the task is purged from the trash, which is recorded
+// as the last event.
+type TaskEvent struct {
+	ID     uint   `json:"id" gorm:"primaryKey"`
+	TaskID uint   `json:"task_id" gorm:"index"`
+	Action string `json:"action"`
+	// Changes holds the fields the event changed. Created events list the
+	// fields the task was created with.
+	Changes TaskChanges `json:"changes" gorm:"serializer:json"`
+	// ActorID is the user who made the change and RequestID the request
+	// it was made in, when known.
+	ActorID   uint      `json:"actor_id"`
+	RequestID string    `json:"request_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID uint `json:"-"`
+}
+
+// TaskChanges maps the JSON names of changed task fields to their values
+// before and after the change.
+type TaskChanges map[string]FieldChange
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
@@ -0,0 +1,67 @@
+// Package rank generates fractional ranking keys: strings that sort in
+// the desired order, where a new key can always be made between two
+// existing ones. Moving an item only rewrites its own key.
+//
+// Keys are base-36 fractions (digits and lowercase letters) without
+// trailing zeros, so they compare the same byte-wise as in most
+// collations.
+package rank
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// Between returns a key that sorts strictly between a and b. An empty a
+// stands for the start of the list and an empty b for its end. If a does
+// not sort before b, the key is placed after a.
+func Between(a, b string) string {
+	if b != "" && a >= b {
+		b = ""
+	}
+	return midpoint(a, b)
+}
+
+func midpoint(a, b string) string {
+	// Keep the common prefix, reading missing digits of a as zeros.
+	n := 0
+	for n < len(b) && digitAt(a, n) == value(b[n]) {
+		n++
+	}
+	if n > 0 {
+		return b[:n] + midpoint(suffix(a, n), b[n:])
+	}
+
+	lo, hi := digitAt(a, 0), len(digits)
+	if b != "" {
+		hi = value(b[0])
+	}
+
+	if hi-lo > 1 {
+		return string(digits[(lo+hi)/2])
+	}
+	// The first digits are adjacent: a shorter prefix of b still sorts
+	// after a, otherwise extend a.
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(digits[lo]) + midpoint(suffix(a, 1), "")
+}
+
+func digitAt(key string, i int) int {
+	if i >= len(key) {
+		return 0
+	}
+	return value(key[i])
+}
+
+func value(c byte) int {
+	if c <= '9' {
+		return int(c - '0')
+	}
+	return int(c-'a') + 10
+}
+
+func suffix(key string, n int) string {
+	if n >= len(key) {
+		return ""
+	}
+	return key[n:]
+}
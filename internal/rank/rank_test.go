@@ -0,0 +1,53 @@
+package rank
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBetween(t *testing.T) {
+	cases := []struct{ a, b string }{
+		{"", ""},
+		{"i", ""},
+		{"", "i"},
+		{"y", "z"},
+		{"z", ""},
+		{"", "0001"},
+		{"a", "a1"},
+		{"00000000011", "00000000021"},
+		{"abc", "abd"},
+	}
+
+	for _, c := range cases {
+		key := Between(c.a, c.b)
+		assert.Greater(t, key, c.a, "%q..%q", c.a, c.b)
+		if c.b != "" {
+			assert.Less(t, key, c.b, "%q..%q", c.a, c.b)
+		}
+		assert.False(t, strings.HasSuffix(key, "0"), key)
+	}
+}
+
+func TestBetweenUnorderedBounds(t *testing.T) {
+	assert.Greater(t, Between("m", "m"), "m")
+	assert.Greater(t, Between("m", "c"), "m")
+}
+
+func TestRepeatedInsertsStayOrdered(t *testing.T) {
+	// Insert repeatedly at the front, the back and in the middle.
+	keys := []string{Between("", "")}
+	for i := 0; i < 200; i++ {
+		keys = append([]string{Between("", keys[0])}, keys...)
+		keys = append(keys, Between(keys[len(keys)-1], ""))
+
+		mid := len(keys) / 2
+		key := Between(keys[mid-1], keys[mid])
+		keys = append(keys[:mid], append([]string{key}, keys[mid:]...)...)
+	}
+
+	for i := 1; i < len(keys); i++ {
+		assert.Less(t, keys[i-1], keys[i])
+	}
+}
@@ -0,0 +1,102 @@
+// Package recurrence computes the occurrences of RFC 5545 recurrence rules
+// (RRULE values such as "FREQ=WEEKLY;BYDAY=MO"). Rules are evaluated from
+// a start time given by the caller, in that time's location, so BYDAY and
+// BYHOUR refer to local days and hours.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+var (
+	ErrInvalidRule     = errors.New("invalid recurrence rule")
+	ErrInvalidTimezone = errors.New("invalid time zone")
+)
+
+// Normalize checks an RRULE value and returns it upper-cased, without an
+// "RRULE:" prefix. DTSTART is rejected because occurrences start from the
+// caller's start time.
+func Normalize(rule string) (string, error) {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	rule = strings.TrimPrefix(rule, "RRULE:")
+
+	if strings.ContainsAny(rule, "\r\n") || strings.Contains(rule, "DTSTART") {
+		return "", fmt.Errorf("%w: DTSTART is not supported", ErrInvalidRule)
+	}
+
+	if _, err := parse(rule, time.Now()); err != nil {
+		return "", err
+	}
+	return rule, nil
+}
+
+// LoadLocation returns the location named by an IANA time zone name such
+// as "Europe/Berlin". The empty name is UTC. "Local" is rejected, as it
+// depends on the server.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, name)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, name)
+	}
+	return loc, nil
+}
+
+func parse(rule string, dtstart time.Time) (*rrule.RRule, error) {
+	option, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRule, err)
+	}
+	option.Dtstart = dtstart
+
+	r, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRule, err)
+	}
+	return r, nil
+}
+
+// Next returns the first occurrence of rule, started at dtstart, that is
+// strictly after after. The rule is expanded in dtstart's location. It
+// reports false when the rule has no further occurrences.
+func Next(rule string, dtstart, after time.Time) (time.Time, bool, error) {
+	r, err := parse(rule, dtstart)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	next := r.After(after, false)
+	return next, !next.IsZero(), nil
+}
+
+// Preview returns up to n occurrences of rule starting at dtstart, which is
+// included when it matches the rule. The rule is expanded in dtstart's
+// location.
+func Preview(rule string, dtstart time.Time, n int) ([]time.Time, error) {
+	r, err := parse(rule, dtstart)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := []time.Time{}
+	next := r.Iterator()
+	for len(occurrences) < n {
+		occurrence, ok := next()
+		if !ok {
+			break
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
@@ -0,0 +1,89 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	rule, err := Normalize(" rrule:freq=weekly;byday=mo ")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", rule)
+
+	for _, rule := range []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=FORTNIGHTLY",
+		"FREQ=DAILY;COLOR=RED",
+		"DTSTART:20250101T000000Z\nRRULE:FREQ=DAILY",
+		"FREQ=DAILY;DTSTART=20250101T000000Z",
+	} {
+		_, err := Normalize(rule)
+		assert.ErrorIs(t, err, ErrInvalidRule, rule)
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2025-01-31 is a Friday.
+	start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	next, ok, err := Next("FREQ=MONTHLY;BYMONTHDAY=-1", start, start)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC), next)
+
+	next, ok, _ = Next("FREQ=WEEKLY;BYDAY=MO,FR", start, start)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC), next)
+
+	_, ok, err = Next("FREQ=DAILY;COUNT=2", start, start.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNextInLocation(t *testing.T) {
+	berlin, err := LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	// Monday 00:30 in Berlin is still Sunday in UTC. Clocks go forward on
+	// 2025-03-30, so the following Monday is an hour closer in UTC.
+	start := time.Date(2025, 3, 24, 0, 30, 0, 0, berlin)
+
+	next, ok, err := Next("FREQ=WEEKLY;BYDAY=MO", start, start)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2025, 3, 30, 22, 30, 0, 0, time.UTC), next.UTC())
+
+	occurrences, err := Preview("FREQ=WEEKLY;BYDAY=MO;BYHOUR=0,9;BYMINUTE=30", start, 3)
+	assert.NoError(t, err)
+	if assert.Len(t, occurrences, 3) {
+		assert.Equal(t, time.Date(2025, 3, 23, 23, 30, 0, 0, time.UTC), occurrences[0].UTC())
+		assert.Equal(t, time.Date(2025, 3, 24, 8, 30, 0, 0, time.UTC), occurrences[1].UTC())
+		assert.Equal(t, time.Date(2025, 3, 30, 22, 30, 0, 0, time.UTC), occurrences[2].UTC())
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	loc, err := LoadLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	for _, name := range []string{"Local", "Mars/Olympus_Mons", "+02:00"} {
+		_, err := LoadLocation(name)
+		assert.ErrorIs(t, err, ErrInvalidTimezone, name)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	occurrences, err := Preview("FREQ=WEEKLY;INTERVAL=2", start, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{start, start.AddDate(0, 0, 14), start.AddDate(0, 0, 28)}, occurrences)
+
+	occurrences, err = Preview("FREQ=YEARLY;COUNT=2", start, 5)
+	assert.NoError(t, err)
+	assert.Len(t, occurrences, 2)
+}
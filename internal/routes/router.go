@@ -1,27 +1,89 @@
 package routes
 
 import (
+	"go-todo-api/internal/blob"
+	"go-todo-api/internal/config"
 	"go-todo-api/internal/handlers"
 	"go-todo-api/internal/middleware"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes() *gin.Engine {
+// Dependencies are the services the HTTP handlers are built from.
+type Dependencies struct {
+	Config *config.Config
+	Tokens *utils.TokenManager
+	Stores store.Stores
+	Blobs  blob.Store
+	Health *handlers.HealthHandler
+}
+
+func SetupRoutes(deps Dependencies) *gin.Engine {
 	r := gin.Default()
+	r.Use(middleware.RequestID())
+
+	r.GET("/healthz", deps.Health.Liveness)
+	r.GET("/readyz", deps.Health.Readiness)
+
+	authHandler := handlers.NewAuthHandler(deps.Config.Auth, deps.Tokens, deps.Stores.Users, deps.Stores.RefreshTokens)
 
-	r.POST("/signup", handlers.Signup)
-	r.POST("/login", handlers.Login)
-	r.POST("/refresh", handlers.RefreshToken)
-	r.POST("/logout", handlers.Logout)
+	r.POST("/signup", authHandler.Signup)
+	r.POST("/login", authHandler.Login)
+	r.POST("/refresh", authHandler.RefreshToken)
+	r.POST("/logout", authHandler.Logout)
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
+	taskHandler := handlers.NewTaskHandler(deps.Stores)
+	projectHandler := handlers.NewProjectHandler(deps.Stores.Projects)
+	tagHandler := handlers.NewTagHandler(deps.Stores.Tags)
+	attachmentHandler := handlers.NewAttachmentHandler(deps.Stores, deps.Blobs, deps.Config.Attachments)
 
 	auth := r.Group("/")
-	auth.Use(middleware.JWTAuthMiddleware())
+	auth.Use(middleware.JWTAuthMiddleware(deps.Tokens))
 	{
-		auth.GET("/tasks", handlers.GetTasks)
-		auth.POST("/tasks", handlers.CreateTask)
-		auth.PUT("/tasks/:id", handlers.UpdateTask)
-		auth.DELETE("/tasks/:id", handlers.DeleteTask)
+		auth.GET("/tasks", taskHandler.GetTasks)
+		auth.GET("/tasks/overdue", taskHandler.GetOverdueTasks)
+		auth.POST("/tasks", taskHandler.CreateTask)
+		auth.GET("/tasks/:id", taskHandler.GetTask)
+		auth.GET("/tasks/:id/subtree", taskHandler.GetSubtree)
+		auth.GET("/tasks/:id/occurrences", taskHandler.GetOccurrences)
+		auth.GET("/tasks/:id/history", taskHandler.GetHistory)
+		auth.GET("/tasks/:id/comments", taskHandler.GetComments)
+		auth.POST("/tasks/:id/comments", taskHandler.CreateComment)
+		auth.PUT("/tasks/:id/comments/:comment_id", taskHandler.UpdateComment)
+		auth.DELETE("/tasks/:id/comments/:comment_id", taskHandler.DeleteComment)
+		auth.GET("/tasks/:id/checklist", taskHandler.GetChecklist)
+		auth.POST("/tasks/:id/checklist", taskHandler.CreateChecklistItem)
+		auth.PUT("/tasks/:id/checklist/order", taskHandler.ReorderChecklist)
+		auth.PATCH("/tasks/:id/checklist/:item_id", taskHandler.UpdateChecklistItem)
+		auth.DELETE("/tasks/:id/checklist/:item_id", taskHandler.DeleteChecklistItem)
+		auth.GET("/tasks/:id/attachments", attachmentHandler.GetAttachments)
+		auth.POST("/tasks/:id/attachments", attachmentHandler.CreateAttachment)
+		auth.GET("/tasks/:id/attachments/:attachment_id", attachmentHandler.DownloadAttachment)
+		auth.DELETE("/tasks/:id/attachments/:attachment_id", attachmentHandler.DeleteAttachment)
+		auth.POST("/tasks/:id/move", taskHandler.MoveTask)
+		auth.PUT("/tasks/:id", taskHandler.UpdateTask)
+		auth.PATCH("/tasks/:id", taskHandler.PatchTask)
+		auth.DELETE("/tasks/:id", taskHandler.DeleteTask)
+		auth.POST("/tasks/:id/restore", taskHandler.RestoreTask)
+		auth.GET("/trash", taskHandler.GetTrash)
+		auth.DELETE("/trash/:id", taskHandler.PurgeTask)
+		auth.POST("/recurrence/preview", taskHandler.PreviewRecurrence)
+
+		auth.GET("/projects", projectHandler.GetProjects)
+		auth.POST("/projects", projectHandler.CreateProject)
+		auth.GET("/projects/:id", projectHandler.GetProject)
+		auth.PUT("/projects/:id", projectHandler.UpdateProject)
+		auth.DELETE("/projects/:id", projectHandler.DeleteProject)
+		auth.GET("/projects/:id/tasks", taskHandler.GetProjectTasks)
+
+		auth.GET("/tags", tagHandler.GetTags)
+		auth.POST("/tags", tagHandler.CreateTag)
+		auth.PUT("/tags/:id", tagHandler.UpdateTag)
+		auth.POST("/tags/:id/merge", tagHandler.MergeTag)
+		auth.DELETE("/tags/:id", tagHandler.DeleteTag)
 	}
 
 	return r
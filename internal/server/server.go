@@ -0,0 +1,126 @@
+// Package server runs the HTTP API together with the background workers
+// that share its lifecycle.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+
+	"go-todo-api/internal/config"
+)
+
+// Worker is a background job. It must return once ctx is cancelled.
+type Worker func(ctx context.Context)
+
+// Server owns the http.Server, the background workers and the resources
+// that must be released once both have stopped.
+type Server struct {
+	cfg        config.ServerConfig
+	http       *http.Server
+	workers    []Worker
+	onDrain    []func()
+	onShutdown []func(ctx context.Context) error
+}
+
+func New(cfg config.ServerConfig, handler http.Handler) *Server {
+	return &Server{
+		cfg: cfg,
+		http: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		},
+	}
+}
+
+// AddWorker registers a background job started by Run.
+func (s *Server) AddWorker(w Worker) {
+	s.workers = append(s.workers, w)
+}
+
+// OnDrain registers a callback run as soon as shutdown starts, before
+// connections are drained, e.g. to fail readiness probes.
+func (s *Server) OnDrain(f func()) {
+	s.onDrain = append(s.onDrain, f)
+}
+
+// OnShutdown registers a cleanup step, such as closing the database, run
+// after connections are drained and workers have stopped. Steps run in
+// reverse registration order.
+func (s *Server) OnShutdown(f func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// Run listens on the configured address and serves until ctx is cancelled.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves on ln until ctx is cancelled, then stops accepting
+// connections, waits up to the shutdown timeout for in-flight requests,
+// stops the workers and runs the shutdown hooks.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var wg sync.WaitGroup
+	for _, w := range s.workers {
+		wg.Add(1)
+		go func(w Worker) {
+			defer wg.Done()
+			w(workerCtx)
+		}(w)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.cfg.TLSCertFile != "" {
+			serveErr <- s.http.ServeTLS(ln, s.cfg.TLSCertFile, s.cfg.TLSKeyFile)
+		} else {
+			serveErr <- s.http.Serve(ln)
+		}
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		// The listener failed before shutdown was requested.
+	case <-ctx.Done():
+		log.Println("Shutting down the server")
+	}
+
+	for _, f := range s.onDrain {
+		f()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := s.http.Shutdown(shutdownCtx); shutdownErr != nil {
+		log.Printf("Error draining connections: %v", shutdownErr)
+		err = errors.Join(err, shutdownErr)
+	}
+
+	stopWorkers()
+	wg.Wait()
+
+	for i := len(s.onShutdown) - 1; i >= 0; i-- {
+		if hookErr := s.onShutdown[i](shutdownCtx); hookErr != nil {
+			log.Printf("Error during shutdown: %v", hookErr)
+			err = errors.Join(err, hookErr)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-todo-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = 5 * time.Second
+	srv := New(cfg, handler)
+
+	var draining, workerStopped, hookCalled atomic.Bool
+	srv.OnDrain(func() { draining.Store(true) })
+	srv.AddWorker(func(ctx context.Context) {
+		<-ctx.Done()
+		workerStopped.Store(true)
+	})
+	srv.OnShutdown(func(ctx context.Context) error {
+		assert.True(t, workerStopped.Load(), "workers must stop before shutdown hooks run")
+		hookCalled.Store(true)
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, http.StatusOK, <-status)
+	assert.NoError(t, <-done)
+	assert.True(t, draining.Load())
+	assert.True(t, hookCalled.Load())
+
+	_, err = http.Get("http://" + ln.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestServeReportsShutdownHookErrors(t *testing.T) {
+	srv := New(config.Default().Server, http.NotFoundHandler())
+	srv.OnShutdown(func(ctx context.Context) error {
+		return errors.New("close failed")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorContains(t, srv.Serve(ctx, ln), "close failed")
+}
@@ -0,0 +1,1529 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/rank"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewGormStores returns stores backed by a gorm database. Each call is
+// cancelled after queryTimeout, unless it is zero.
+func NewGormStores(db *gorm.DB, queryTimeout time.Duration) Stores {
+	conn := gormConn{db: db, timeout: queryTimeout}
+	return Stores{
+		Tasks:         &GormTaskStore{conn},
+		Projects:      &GormProjectStore{conn},
+		Tags:          &GormTagStore{conn},
+		Comments:      &GormCommentStore{conn},
+		Attachments:   &GormAttachmentStore{conn},
+		Checklists:    &GormChecklistStore{conn},
+		Users:         &GormUserStore{conn},
+		RefreshTokens: &GormRefreshTokenStore{conn},
+	}
+}
+
+type gormConn struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+// session returns the database bound to ctx, limited to the query timeout.
+// The cancel function must be called once the call is done.
+func (c gormConn) session(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return c.db.WithContext(ctx), func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	return c.db.WithContext(ctx), cancel
+}
+
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	default:
+		return err
+	}
+}
+
+type GormTaskStore struct {
+	gormConn
+}
+
+func (s *GormTaskStore) List(ctx context.Context, userID uint, opts TaskListOptions) (TaskList, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var list TaskList
+
+	query := applyTaskFilter(db.Model(&models.Task{}).Where("user_id = ?", userID), opts.Filter).
+		Session(&gorm.Session{})
+
+	if err := query.Count(&list.Total).Error; err != nil {
+		return list, err
+	}
+
+	pageQuery, err := applyTaskCursor(query, opts)
+	if err != nil {
+		return list, err
+	}
+
+	pageQuery = applyTaskOrder(pageQuery, opts).Offset(opts.Offset)
+	if opts.Limit > 0 {
+		pageQuery = pageQuery.Limit(opts.Limit + 1)
+	}
+
+	list.Tasks = []models.Task{}
+	if err := pageQuery.Find(&list.Tasks).Error; err != nil {
+		return list, err
+	}
+
+	if opts.Limit > 0 && len(list.Tasks) > opts.Limit {
+		list.Tasks = list.Tasks[:opts.Limit]
+		list.HasMore = true
+	}
+
+	return list, loadTaskDetails(db, list.Tasks)
+}
+
+const overdueCondition = "done = ? AND due_at IS NOT NULL AND due_at < ?"
+
+func applyTaskFilter(query *gorm.DB, filter TaskFilter) *gorm.DB {
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	}
+
+	if filter.InboxOnly {
+		query = query.Where("project_id IS NULL")
+	}
+
+	if filter.ParentID != nil {
+		query = query.Where("parent_id = ?", *filter.ParentID)
+	}
+
+	if filter.RootOnly {
+		query = query.Where("parent_id IS NULL")
+	}
+
+	if len(filter.Tags) > 0 {
+		names := uniqueTagNames(filter.Tags)
+		tagged := query.Session(&gorm.Session{NewDB: true}).
+			Table("task_tags").
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.name IN ?", names)
+		if filter.AllTags {
+			tagged = tagged.Group("task_tags.task_id").Having("COUNT(*) = ?", len(names))
+		}
+		query = query.Where("id IN (?)", tagged)
+	}
+
+	if filter.DueBefore != nil {
+		query = query.Where("due_at < ?", filter.DueBefore.UTC())
+	}
+
+	if filter.DueAfter != nil {
+		query = query.Where("due_at > ?", filter.DueAfter.UTC())
+	}
+
+	if filter.Overdue != nil {
+		if *filter.Overdue {
+			query = query.Where(overdueCondition, false, filter.Now.UTC())
+		} else {
+			query = query.Where("NOT ("+overdueCondition+")", false, filter.Now.UTC())
+		}
+	}
+
+	if filter.Done != nil {
+		query = query.Where("done = ?", *filter.Done)
+	}
+
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", filter.UpdatedSince.UTC())
+	}
+
+	if filter.Title != "" {
+		query = query.Where("LOWER(title) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(filter.Title))+"%")
+	}
+
+	return query
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// applyTaskOrder sorts by the requested column with NULLs last, using the id
+// as a tie breaker so cursors are stable.
+func applyTaskOrder(query *gorm.DB, opts TaskListOptions) *gorm.DB {
+	direction := "ASC"
+	if opts.Desc {
+		direction = "DESC"
+	}
+
+	if opts.Sort == "" || opts.Sort == "id" {
+		return query.Order("id " + direction)
+	}
+
+	return query.
+		Order(fmt.Sprintf("CASE WHEN %s IS NULL THEN 1 ELSE 0 END", opts.Sort)).
+		Order(fmt.Sprintf("%s %s", opts.Sort, direction)).
+		Order("id " + direction)
+}
+
+// applyTaskCursor restricts the query to rows after the cursor position.
+func applyTaskCursor(query *gorm.DB, opts TaskListOptions) (*gorm.DB, error) {
+	cursor := opts.After
+	if cursor == nil {
+		return query, nil
+	}
+
+	cmp := ">"
+	if opts.Desc {
+		cmp = "<"
+	}
+
+	if opts.Sort == "" || opts.Sort == "id" {
+		return query.Where("id "+cmp+" ?", cursor.ID), nil
+	}
+
+	column := opts.Sort
+	if _, ok := TaskSortFields[column]; !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.Value == nil {
+		return query.Where(fmt.Sprintf("%s IS NULL AND id %s ?", column, cmp), cursor.ID), nil
+	}
+
+	value, err := parseSortValue(column, *cursor.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	return query.Where(
+		fmt.Sprintf("(%[1]s IS NULL OR %[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, cmp),
+		value, value, cursor.ID,
+	), nil
+}
+
+func (s *GormTaskStore) Get(ctx context.Context, userID, id uint) (models.Task, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var task models.Task
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&task).Error; err != nil {
+		return task, translateError(err)
+	}
+
+	tasks := []models.Task{task}
+	err := loadTaskDetails(db, tasks)
+	return tasks[0], err
+}
+
+func (s *GormTaskStore) Subtree(ctx context.Context, userID, id uint) ([]models.Task, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	ids, err := subtreeIDs(db, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrNotFound
+	}
+
+	var tasks []models.Task
+	if err := db.Where("id IN ?", ids).Order("id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, loadTaskDetails(db, tasks)
+}
+
+// subtreeIDs returns the ids of the user's task id and of its descendants,
+// leaving out trashed tasks.
+func subtreeIDs(db *gorm.DB, userID, id uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(`WITH RECURSIVE subtree (id) AS (
+			SELECT id FROM tasks WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+			UNION
+			SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+			WHERE tasks.deleted_at IS NULL
+		)
+		SELECT id FROM subtree`, id, userID).Scan(&ids).Error
+	return ids, err
+}
+
+// trashedSubtreeIDs returns the ids of the user's trashed task id and of the
+// descendants that were trashed along with it, which share its deletion
+// time.
+func trashedSubtreeIDs(db *gorm.DB, userID, id uint) ([]uint, error) {
+	var ids []uint
+	err := db.Raw(`WITH RECURSIVE subtree (id, deleted_at) AS (
+			SELECT id, deleted_at FROM tasks WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL
+			UNION
+			SELECT tasks.id, tasks.deleted_at FROM tasks
+			JOIN subtree ON tasks.parent_id = subtree.id AND tasks.deleted_at = subtree.deleted_at
+		)
+		SELECT id FROM subtree`, id, userID).Scan(&ids).Error
+	return ids, err
+}
+
+// lockUser locks userID's row until the end of the transaction, so that
+// positions are assigned to the user's tasks one transaction at a time.
+// SQLite already runs one write transaction at a time.
+func lockUser(tx *gorm.DB, userID uint) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Error
+}
+
+func (s *GormTaskStore) Create(ctx context.Context, task *models.Task) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if task.Position == "" {
+			if err := lockUser(tx, task.UserID); err != nil {
+				return err
+			}
+
+			// Trashed tasks keep their position, so they count too.
+			var last string
+			err := tx.Unscoped().Model(&models.Task{}).Where("user_id = ?", task.UserID).
+				Select("COALESCE(MAX(position), '')").Scan(&last).Error
+			if err != nil {
+				return err
+			}
+			task.Position = rank.Between(last, "")
+		}
+
+		now := time.Now().UTC()
+		task.CreatedAt, task.UpdatedAt = now, now
+		trackCompletion(task, nil)
+
+		if err := tx.Create(task).Error; err != nil {
+			return translateError(err)
+		}
+		if err := syncTaskTags(tx, task); err != nil {
+			return err
+		}
+		if err := recordTaskEvent(tx, task.UserID, task.ID, models.TaskCreated, diffTask(nil, *task)); err != nil {
+			return err
+		}
+		return touchParents(tx, task.ParentID)
+	})
+}
+
+func (s *GormTaskStore) Update(ctx context.Context, task *models.Task) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return updateTask(tx, task)
+	})
+}
+
+func updateTask(tx *gorm.DB, task *models.Task) error {
+	stored := make([]models.Task, 1)
+	if err := tx.First(&stored[0], task.ID).Error; err != nil {
+		return translateError(err)
+	}
+	if err := loadTaskDetails(tx, stored); err != nil {
+		return err
+	}
+	old := stored[0]
+	task.CreatedAt = old.CreatedAt
+	trackCompletion(task, &old)
+
+	if err := tx.Save(task).Error; err != nil {
+		return translateError(err)
+	}
+	if err := syncTaskTags(tx, task); err != nil {
+		return err
+	}
+	if changes := diffTask(&old, *task); len(changes) > 0 {
+		if err := recordTaskEvent(tx, task.UserID, task.ID, models.TaskUpdated, changes); err != nil {
+			return err
+		}
+	}
+
+	moved := !equalIDs(old.ParentID, task.ParentID)
+	if moved || old.Done != task.Done {
+		if err := touchParents(tx, old.ParentID); err != nil {
+			return err
+		}
+	}
+	if moved {
+		return touchParents(tx, task.ParentID)
+	}
+	return nil
+}
+
+func (s *GormTaskStore) Move(ctx context.Context, task *models.Task, anchorID uint, after bool) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := lockUser(tx, task.UserID); err != nil {
+			return err
+		}
+
+		var anchor models.Task
+		if err := tx.Where("id = ? AND user_id = ?", anchorID, task.UserID).First(&anchor).Error; err != nil {
+			return translateError(err)
+		}
+
+		// Find the neighbour on the other side of the anchor, in the order
+		// used when sorting by position. Trashed tasks keep their position,
+		// so they count too.
+		query := tx.Unscoped().Model(&models.Task{}).Where("user_id = ? AND id NOT IN ?", task.UserID, []uint{task.ID, anchor.ID})
+		if after {
+			query = query.Where("position > ? OR (position = ? AND id > ?)", anchor.Position, anchor.Position, anchor.ID).
+				Order("position ASC").Order("id ASC")
+		} else {
+			query = query.Where("position < ? OR (position = ? AND id < ?)", anchor.Position, anchor.Position, anchor.ID).
+				Order("position DESC").Order("id DESC")
+		}
+
+		var neighbors []string
+		if err := query.Limit(1).Pluck("position", &neighbors).Error; err != nil {
+			return err
+		}
+
+		var neighbor *string
+		if len(neighbors) > 0 {
+			neighbor = &neighbors[0]
+		}
+		from := task.Position
+		task.Position = positionNextTo(anchor.Position, neighbor, after)
+
+		result := tx.Model(task).Updates(map[string]interface{}{
+			"position": task.Position,
+			"version":  task.Version,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return recordTaskEvent(tx, task.UserID, task.ID, models.TaskUpdated, change("position", from, task.Position))
+	})
+}
+
+func (s *GormTaskStore) CompleteOccurrence(ctx context.Context, task *models.Task, occurrence *models.TaskOccurrence) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := updateTask(tx, task); err != nil {
+			return err
+		}
+
+		occurrence.TaskID = task.ID
+		return tx.Create(occurrence).Error
+	})
+}
+
+func (s *GormTaskStore) Occurrences(ctx context.Context, userID, taskID uint) ([]models.TaskOccurrence, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	occurrences := []models.TaskOccurrence{}
+	err := db.Joins("JOIN tasks ON tasks.id = task_occurrences.task_id").
+		Where("task_occurrences.task_id = ? AND tasks.user_id = ? AND tasks.deleted_at IS NULL", taskID, userID).
+		Order("task_occurrences.completed_at DESC").
+		Order("task_occurrences.id DESC").
+		Find(&occurrences).Error
+	return occurrences, err
+}
+
+func (s *GormTaskStore) History(ctx context.Context, userID, taskID uint) ([]models.TaskEvent, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	events := []models.TaskEvent{}
+	err := db.Where("task_id = ? AND user_id = ?", taskID, userID).
+		Order("created_at").Order("id").
+		Find(&events).Error
+	return events, err
+}
+
+// recordTaskEvent adds an event to the history of one of userID's tasks,
+// attributed to the actor of the transaction's context.
+func recordTaskEvent(tx *gorm.DB, userID, taskID uint, action string, changes models.TaskChanges) error {
+	event := newTaskEvent(tx.Statement.Context, userID, taskID, action, changes)
+	return tx.Create(&event).Error
+}
+
+func equalIDs(a, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// touchParents records a change to the subtasks of parentID: it bumps the
+// parent's version and, if the parent auto-completes, syncs its Done flag
+// with its subtasks. A parent whose Done flag changes is itself a changed
+// subtask, so this continues up the tree. Recurring parents are not
+// auto-completed, as completing one moves it to its next occurrence.
+func touchParents(tx *gorm.DB, parentID *uint) error {
+	for parentID != nil {
+		var parent models.Task
+		if err := tx.First(&parent, *parentID).Error; err != nil {
+			return translateError(err)
+		}
+
+		updates := map[string]interface{}{"version": gorm.Expr("version + 1")}
+		if parent.AutoComplete && parent.RRule == "" {
+			progress, err := subtaskProgress(tx, parent.ID)
+			if err != nil {
+				return err
+			}
+			if done := progress.Done == progress.Total; progress.Total > 0 && done != parent.Done {
+				updates["done"] = done
+				updates["completed_at"] = completionUpdate(done)
+			}
+		}
+
+		// Updates writes the new values to parent as well.
+		wasDone := parent.Done
+		if err := tx.Model(&parent).Updates(updates).Error; err != nil {
+			return err
+		}
+		done, changed := updates["done"]
+		if !changed {
+			return nil
+		}
+		if err := recordTaskEvent(tx, parent.UserID, parent.ID, models.TaskUpdated, change("done", wasDone, done)); err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+func subtaskProgress(tx *gorm.DB, parentID uint) (models.SubtaskProgress, error) {
+	var progress models.SubtaskProgress
+	err := tx.Model(&models.Task{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN done THEN 1 ELSE 0 END), 0) AS done").
+		Where("parent_id = ?", parentID).
+		Scan(&progress).Error
+	return progress, err
+}
+
+// taskTag is a row of the join table between tasks and tags.
+type taskTag struct {
+	TaskID uint
+	TagID  uint
+}
+
+func (taskTag) TableName() string {
+	return "task_tags"
+}
+
+// loadTaskDetails fills in the fields of tasks that are not stored in the
+// tasks table: their tag names, subtask and checklist progress and comment
+// counts.
+func loadTaskDetails(db *gorm.DB, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(tasks))
+	index := make(map[uint]int, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+		index[tasks[i].ID] = i
+		tasks[i].Tags = []string{}
+		tasks[i].Subtasks = models.SubtaskProgress{}
+		tasks[i].Checklist = models.ChecklistProgress{}
+		tasks[i].CommentCount = 0
+	}
+
+	var progress []struct {
+		ParentID uint
+		models.SubtaskProgress
+	}
+	err := db.Model(&models.Task{}).
+		Select("parent_id, COUNT(*) AS total, COALESCE(SUM(CASE WHEN done THEN 1 ELSE 0 END), 0) AS done").
+		Where("parent_id IN ?", ids).
+		Group("parent_id").
+		Scan(&progress).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range progress {
+		tasks[index[row.ParentID]].Subtasks = row.SubtaskProgress
+	}
+
+	var checklists []struct {
+		TaskID uint
+		models.ChecklistProgress
+	}
+	err = db.Model(&models.ChecklistItem{}).
+		Select("task_id, COUNT(*) AS total, COALESCE(SUM(CASE WHEN checked THEN 1 ELSE 0 END), 0) AS checked").
+		Where("task_id IN ?", ids).
+		Group("task_id").
+		Scan(&checklists).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range checklists {
+		tasks[index[row.TaskID]].Checklist = row.ChecklistProgress
+	}
+
+	tags, err := taskTagNames(db, ids)
+	if err != nil {
+		return err
+	}
+	for id, names := range tags {
+		tasks[index[id]].Tags = names
+	}
+
+	var counts []struct {
+		TaskID uint
+		Count  int64
+	}
+	err = db.Model(&models.Comment{}).
+		Select("task_id, COUNT(*) AS count").
+		Where("task_id IN ?", ids).
+		Group("task_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range counts {
+		tasks[index[row.TaskID]].CommentCount = row.Count
+	}
+	return nil
+}
+
+// taskTagNames returns the sorted tag names of each of the tasks in ids.
+func taskTagNames(db *gorm.DB, ids []uint) (map[uint][]string, error) {
+	var rows []struct {
+		TaskID uint
+		Name   string
+	}
+	err := db.Table("task_tags").
+		Select("task_tags.task_id, tags.name").
+		Joins("JOIN tags ON tags.id = task_tags.tag_id").
+		Where("task_tags.task_id IN ?", ids).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint][]string, len(ids))
+	for _, id := range ids {
+		names[id] = []string{}
+	}
+	for _, row := range rows {
+		names[row.TaskID] = append(names[row.TaskID], row.Name)
+	}
+	return names, nil
+}
+
+// syncTaskTags replaces the tags attached to task with task.Tags, creating
+// the tags the user doesn't have yet.
+func syncTaskTags(tx *gorm.DB, task *models.Task) error {
+	task.Tags = uniqueTagNames(task.Tags)
+
+	if err := tx.Where("task_id = ?", task.ID).Delete(&taskTag{}).Error; err != nil {
+		return err
+	}
+	if len(task.Tags) == 0 {
+		return nil
+	}
+
+	tags := make([]models.Tag, len(task.Tags))
+	for i, name := range task.Tags {
+		tags[i] = models.Tag{Name: name, UserID: task.UserID}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+		return err
+	}
+
+	tags = nil
+	if err := tx.Where("user_id = ? AND name IN ?", task.UserID, task.Tags).Find(&tags).Error; err != nil {
+		return err
+	}
+
+	links := make([]taskTag, len(tags))
+	for i, tag := range tags {
+		links[i] = taskTag{TaskID: task.ID, TagID: tag.ID}
+	}
+	return tx.Create(&links).Error
+}
+
+func (s *GormTaskStore) Delete(ctx context.Context, task *models.Task, deleteSubtasks bool) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		ids := []uint{task.ID}
+		if deleteSubtasks {
+			var err error
+			if ids, err = subtreeIDs(tx, task.UserID, task.ID); err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", ids).Delete(&models.Task{}).Error; err != nil {
+				return err
+			}
+		} else {
+			var children []uint
+			if err := tx.Model(&models.Task{}).Where("parent_id = ?", task.ID).Pluck("id", &children).Error; err != nil {
+				return err
+			}
+			err := tx.Model(&models.Task{}).Where("parent_id = ?", task.ID).Updates(map[string]interface{}{
+				"parent_id": task.ParentID,
+				"version":   gorm.Expr("version + 1"),
+			}).Error
+			if err != nil {
+				return err
+			}
+			for _, id := range children {
+				if err := recordTaskEvent(tx, task.UserID, id, models.TaskUpdated, change("parent_id", task.ID, task.ParentID)); err != nil {
+					return err
+				}
+			}
+			if err := tx.Delete(task).Error; err != nil {
+				return translateError(err)
+			}
+		}
+
+		for _, id := range ids {
+			if err := recordTaskEvent(tx, task.UserID, id, models.TaskDeleted, nil); err != nil {
+				return err
+			}
+		}
+		return touchParents(tx, task.ParentID)
+	})
+}
+
+func (s *GormTaskStore) Trash(ctx context.Context, userID uint) ([]models.Task, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	tasks := []models.Task{}
+	err := db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").Order("id DESC").
+		Find(&tasks).Error
+	if err != nil {
+		return tasks, err
+	}
+	return tasks, loadTaskDetails(db, tasks)
+}
+
+func (s *GormTaskStore) GetTrashed(ctx context.Context, userID, id uint) (models.Task, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var task models.Task
+	err := db.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).First(&task).Error
+	if err != nil {
+		return task, translateError(err)
+	}
+
+	tasks := []models.Task{task}
+	err = loadTaskDetails(db, tasks)
+	return tasks[0], err
+}
+
+func (s *GormTaskStore) Restore(ctx context.Context, task *models.Task) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedSubtreeIDs(tx, task.UserID, task.ID)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return ErrNotFound
+		}
+
+		var detached models.TaskChanges
+		if task.ParentID != nil {
+			var live int64
+			if err := tx.Model(&models.Task{}).Where("id = ?", *task.ParentID).Count(&live).Error; err != nil {
+				return err
+			}
+			if live == 0 {
+				detached = change("parent_id", *task.ParentID, nil)
+				task.ParentID = nil
+				if err := tx.Unscoped().Model(task).Update("parent_id", nil).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		err = tx.Unscoped().Model(&models.Task{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			var changes models.TaskChanges
+			if id == task.ID {
+				changes = detached
+			}
+			if err := recordTaskEvent(tx, task.UserID, id, models.TaskRestored, changes); err != nil {
+				return err
+			}
+		}
+
+		return touchParents(tx, task.ParentID)
+	})
+}
+
+func (s *GormTaskStore) Purge(ctx context.Context, task *models.Task) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedSubtreeIDs(tx, task.UserID, task.ID)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return ErrNotFound
+		}
+		return purgeTasks(tx, ids)
+	})
+}
+
+// purgeBatchSize bounds the number of tasks PurgeDeleted removes per
+// statement.
+const purgeBatchSize = 500
+
+func (s *GormTaskStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	for {
+		n, err := s.purgeBatch(ctx, before)
+		purged += n
+		if err != nil {
+			return purged, err
+		}
+		if n < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purgeBatch purges up to purgeBatchSize of the tasks deleted before
+// before. Each batch has a session of its own, so the query timeout bounds
+// a batch rather than the whole purge.
+func (s *GormTaskStore) purgeBatch(ctx context.Context, before time.Time) (int64, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var ids []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.Task{}).Where("deleted_at < ?", before.UTC()).
+			Order("id").Limit(purgeBatchSize).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		return purgeTasks(tx, ids)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+// purgeTasks permanently deletes the given tasks. Their tags, occurrences,
+// comments and attachments go with them, the attachments' blobs are queued
+// for the sweeper, and tasks left in the trash below them lose their
+// parent. Their history is kept, ending with a purged event.
+func purgeTasks(tx *gorm.DB, ids []uint) error {
+	var owners []struct {
+		ID     uint
+		UserID uint
+	}
+	err := tx.Unscoped().Model(&models.Task{}).Select("id, user_id").Where("id IN ?", ids).Scan(&owners).Error
+	if err != nil {
+		return err
+	}
+
+	events := make([]models.TaskEvent, len(owners))
+	for i, owner := range owners {
+		events[i] = newTaskEvent(tx.Statement.Context, owner.UserID, owner.ID, models.TaskPurged, nil)
+	}
+	if len(events) > 0 {
+		if err := tx.Create(&events).Error; err != nil {
+			return err
+		}
+	}
+
+	err = tx.Exec("INSERT INTO orphaned_blobs (blob_key, created_at) SELECT blob_key, ? FROM attachments WHERE task_id IN ?",
+		time.Now().UTC(), ids).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Unscoped().Model(&models.Task{}).
+		Where("parent_id IN ? AND id NOT IN ?", ids, ids).
+		Update("parent_id", nil).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Task{}).Error
+}
+
+type GormProjectStore struct {
+	gormConn
+}
+
+func (s *GormProjectStore) List(ctx context.Context, userID uint, includeArchived bool) ([]models.Project, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	query := db.Where("user_id = ?", userID)
+	if !includeArchived {
+		query = query.Where("archived = ?", false)
+	}
+
+	projects := []models.Project{}
+	err := query.Order("position ASC").Order("id ASC").Find(&projects).Error
+	return projects, err
+}
+
+func (s *GormProjectStore) Get(ctx context.Context, userID, id uint) (models.Project, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var project models.Project
+	err := db.Where("id = ? AND user_id = ?", id, userID).First(&project).Error
+	return project, translateError(err)
+}
+
+func (s *GormProjectStore) Create(ctx context.Context, project *models.Project) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if project.Position == 0 {
+			var last int
+			err := tx.Model(&models.Project{}).Where("user_id = ?", project.UserID).
+				Select("COALESCE(MAX(position), 0)").Scan(&last).Error
+			if err != nil {
+				return err
+			}
+			project.Position = last + 1
+		}
+
+		return translateError(tx.Create(project).Error)
+	})
+}
+
+func (s *GormProjectStore) Update(ctx context.Context, project *models.Project) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return translateError(db.Save(project).Error)
+}
+
+func (s *GormProjectStore) Delete(ctx context.Context, project *models.Project, deleteTasks bool) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Model(&models.Task{}).Where("project_id = ? AND user_id = ?", project.ID, project.UserID).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return err
+		}
+
+		if deleteTasks {
+			// Subtasks kept in other projects lose their parent.
+			var detached []models.Task
+			err := tx.Select("id", "parent_id").
+				Where("parent_id IN ?", ids).
+				Where("project_id IS NULL OR project_id <> ?", project.ID).
+				Find(&detached).Error
+			if err != nil {
+				return err
+			}
+
+			for _, subtask := range detached {
+				err := tx.Model(&subtask).Updates(map[string]interface{}{
+					"parent_id": nil,
+					"version":   gorm.Expr("version + 1"),
+				}).Error
+				if err != nil {
+					return err
+				}
+				if err := recordTaskEvent(tx, project.UserID, subtask.ID, models.TaskUpdated, change("parent_id", subtask.ParentID, nil)); err != nil {
+					return err
+				}
+			}
+
+			// Parents outside the project lose subtasks, so they are
+			// touched like on a single delete.
+			var parentIDs []uint
+			err = tx.Model(&models.Task{}).Distinct("parent_id").
+				Where("id IN ? AND parent_id IS NOT NULL AND parent_id NOT IN ?", ids, ids).
+				Pluck("parent_id", &parentIDs).Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Where("id IN ?", ids).Delete(&models.Task{}).Error; err != nil {
+				return err
+			}
+
+			for i := range parentIDs {
+				if err := touchParents(tx, &parentIDs[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, id := range ids {
+			changes := change("project_id", project.ID, nil)
+			action := models.TaskUpdated
+			if deleteTasks {
+				changes, action = nil, models.TaskDeleted
+			}
+			if err := recordTaskEvent(tx, project.UserID, id, action, changes); err != nil {
+				return err
+			}
+		}
+
+		// The remaining tasks, including trashed ones, move to the Inbox
+		// so that none refers to the deleted project.
+		err = tx.Unscoped().Model(&models.Task{}).
+			Where("project_id = ? AND user_id = ?", project.ID, project.UserID).
+			Updates(map[string]interface{}{
+				"project_id": nil,
+				"version":    gorm.Expr("version + 1"),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return translateError(tx.Delete(project).Error)
+	})
+}
+
+type GormTagStore struct {
+	gormConn
+}
+
+// withTaskCounts selects tags together with the number of live tasks they
+// are attached to.
+func withTaskCounts(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Tag{}).
+		Select("tags.id, tags.name, tags.user_id, COUNT(tasks.id) AS task_count").
+		Joins("LEFT JOIN task_tags ON task_tags.tag_id = tags.id").
+		Joins("LEFT JOIN tasks ON tasks.id = task_tags.task_id AND tasks.deleted_at IS NULL").
+		Group("tags.id, tags.name, tags.user_id")
+}
+
+func (s *GormTagStore) List(ctx context.Context, userID uint) ([]models.Tag, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	tags := []models.Tag{}
+	err := withTaskCounts(db).Where("tags.user_id = ?", userID).Order("tags.name").Find(&tags).Error
+	return tags, err
+}
+
+func (s *GormTagStore) Get(ctx context.Context, userID, id uint) (models.Tag, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var tag models.Tag
+	err := withTaskCounts(db).Where("tags.id = ? AND tags.user_id = ?", id, userID).First(&tag).Error
+	return tag, translateError(err)
+}
+
+func (s *GormTagStore) Create(ctx context.Context, tag *models.Tag) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return translateError(db.Create(tag).Error)
+}
+
+func (s *GormTagStore) Update(ctx context.Context, tag *models.Tag) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return retagTasks(tx, tag.ID, func() error {
+			return translateError(tx.Model(tag).Update("name", tag.Name).Error)
+		})
+	})
+}
+
+func (s *GormTagStore) Merge(ctx context.Context, source, target *models.Tag) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return retagTasks(tx, source.ID, func() error {
+			err := tx.Exec(`INSERT INTO task_tags (task_id, tag_id)
+				SELECT task_id, ? FROM task_tags
+				WHERE tag_id = ? AND task_id NOT IN (SELECT task_id FROM task_tags WHERE tag_id = ?)`,
+				target.ID, source.ID, target.ID).Error
+			if err != nil {
+				return err
+			}
+
+			return deleteTag(tx, source)
+		})
+	})
+}
+
+func (s *GormTagStore) Delete(ctx context.Context, tag *models.Tag) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return retagTasks(tx, tag.ID, func() error {
+			return deleteTag(tx, tag)
+		})
+	})
+}
+
+// retagTasks runs apply, which changes the tags of the tasks carrying tagID,
+// then bumps those tasks' versions and records the change in their history.
+// Trashed tasks are included.
+func retagTasks(tx *gorm.DB, tagID uint, apply func() error) error {
+	var tasks []models.Task
+	tagged := tx.Session(&gorm.Session{NewDB: true}).Model(&taskTag{}).Select("task_id").Where("tag_id = ?", tagID)
+	if err := tx.Unscoped().Select("id", "user_id").Where("id IN (?)", tagged).Find(&tasks).Error; err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return apply()
+	}
+
+	ids := make([]uint, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	before, err := taskTagNames(tx, ids)
+	if err != nil {
+		return err
+	}
+	if err := apply(); err != nil {
+		return err
+	}
+	after, err := taskTagNames(tx, ids)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Unscoped().Model(&models.Task{}).Where("id IN ?", ids).Update("version", gorm.Expr("version + 1")).Error
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if err := recordTaskEvent(tx, task.UserID, task.ID, models.TaskUpdated, change("tags", before[task.ID], after[task.ID])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteTag(tx *gorm.DB, tag *models.Tag) error {
+	if err := tx.Where("tag_id = ?", tag.ID).Delete(&taskTag{}).Error; err != nil {
+		return err
+	}
+	return translateError(tx.Delete(tag).Error)
+}
+
+type GormCommentStore struct {
+	gormConn
+}
+
+func (s *GormCommentStore) List(ctx context.Context, taskID uint, limit, offset int) (CommentList, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	list := CommentList{Comments: []models.Comment{}}
+	query := db.Model(&models.Comment{}).Where("task_id = ?", taskID).Session(&gorm.Session{})
+	if err := query.Count(&list.Total).Error; err != nil {
+		return list, err
+	}
+
+	query = query.Order("created_at").Order("id").Offset(offset)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return list, query.Find(&list.Comments).Error
+}
+
+func (s *GormCommentStore) Get(ctx context.Context, taskID, id uint) (models.Comment, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var comment models.Comment
+	err := db.Where("id = ? AND task_id = ?", id, taskID).First(&comment).Error
+	return comment, translateError(err)
+}
+
+func (s *GormCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+		comment.CreatedAt, comment.UpdatedAt = now, now
+
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		return bumpTask(tx, comment.TaskID)
+	})
+}
+
+func (s *GormCommentStore) Update(ctx context.Context, comment *models.Comment) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	comment.UpdatedAt = time.Now().UTC()
+	result := db.Model(comment).Updates(map[string]interface{}{
+		"body":       comment.Body,
+		"updated_at": comment.UpdatedAt,
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+func (s *GormCommentStore) Delete(ctx context.Context, comment *models.Comment) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(comment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return bumpTask(tx, comment.TaskID)
+	})
+}
+
+type GormAttachmentStore struct {
+	gormConn
+}
+
+// orphanedBlob is a row of the queue of blobs waiting to be deleted.
+type orphanedBlob struct {
+	Key       string `gorm:"column:blob_key;primaryKey"`
+	CreatedAt time.Time
+}
+
+func (orphanedBlob) TableName() string {
+	return "orphaned_blobs"
+}
+
+func (s *GormAttachmentStore) List(ctx context.Context, taskID uint) ([]models.Attachment, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	attachments := []models.Attachment{}
+	err := db.Where("task_id = ?", taskID).Order("created_at").Order("id").Find(&attachments).Error
+	return attachments, err
+}
+
+func (s *GormAttachmentStore) Get(ctx context.Context, taskID, id uint) (models.Attachment, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var attachment models.Attachment
+	err := db.Where("id = ? AND task_id = ?", id, taskID).First(&attachment).Error
+	return attachment, translateError(err)
+}
+
+func (s *GormAttachmentStore) Create(ctx context.Context, attachment *models.Attachment) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	attachment.CreatedAt = time.Now().UTC()
+	return db.Create(attachment).Error
+}
+
+func (s *GormAttachmentStore) Delete(ctx context.Context, attachment *models.Attachment) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(attachment)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Create(&orphanedBlob{Key: attachment.Key, CreatedAt: time.Now().UTC()}).Error
+	})
+}
+
+func (s *GormAttachmentStore) OrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	keys := []string{}
+	err := db.Model(&orphanedBlob{}).Order("created_at").Order("blob_key").Limit(limit).Pluck("blob_key", &keys).Error
+	return keys, err
+}
+
+func (s *GormAttachmentStore) ForgetBlobs(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Where("blob_key IN ?", keys).Delete(&orphanedBlob{}).Error
+}
+
+type GormChecklistStore struct {
+	gormConn
+}
+
+func (s *GormChecklistStore) List(ctx context.Context, taskID uint) ([]models.ChecklistItem, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	items := []models.ChecklistItem{}
+	err := db.Where("task_id = ?", taskID).Order("position").Order("id").Find(&items).Error
+	return items, err
+}
+
+func (s *GormChecklistStore) Get(ctx context.Context, taskID, id uint) (models.ChecklistItem, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var item models.ChecklistItem
+	err := db.Where("id = ? AND task_id = ?", id, taskID).First(&item).Error
+	return item, translateError(err)
+}
+
+func (s *GormChecklistStore) Create(ctx context.Context, item *models.ChecklistItem) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&models.ChecklistItem{}).Where("task_id = ?", item.TaskID).
+			Select("COALESCE(MAX(position), 0)").Scan(&last).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		item.Position = last + 1
+		item.CreatedAt, item.UpdatedAt = now, now
+		if err := tx.Create(item).Error; err != nil {
+			return err
+		}
+		return touchChecklist(tx, item.TaskID)
+	})
+}
+
+func (s *GormChecklistStore) Update(ctx context.Context, item *models.ChecklistItem) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		item.UpdatedAt = time.Now().UTC()
+		result := tx.Model(item).Updates(map[string]interface{}{
+			"text":       item.Text,
+			"checked":    item.Checked,
+			"updated_at": item.UpdatedAt,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return touchChecklist(tx, item.TaskID)
+	})
+}
+
+func (s *GormChecklistStore) Delete(ctx context.Context, item *models.ChecklistItem) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(item)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return touchChecklist(tx, item.TaskID)
+	})
+}
+
+func (s *GormChecklistStore) Reorder(ctx context.Context, taskID uint, ids []uint) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var current []uint
+		if err := tx.Model(&models.ChecklistItem{}).Where("task_id = ?", taskID).Pluck("id", &current).Error; err != nil {
+			return err
+		}
+		if !samePermutation(current, ids) {
+			return ErrInvalidOrder
+		}
+
+		now := time.Now().UTC()
+		for i, id := range ids {
+			err := tx.Model(&models.ChecklistItem{}).Where("id = ?", id).
+				Updates(map[string]interface{}{"position": i + 1, "updated_at": now}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return bumpTask(tx, taskID)
+	})
+}
+
+// touchChecklist records a change to the checklist of a task: it bumps the
+// task's version and, if the task follows its checklist, syncs its Done
+// flag, which in turn is a change to the subtasks of its parent. Recurring
+// tasks are left alone, as completing one moves it to its next occurrence.
+func touchChecklist(tx *gorm.DB, taskID uint) error {
+	var task models.Task
+	if err := tx.First(&task, taskID).Error; err != nil {
+		return translateError(err)
+	}
+
+	updates := map[string]interface{}{"version": gorm.Expr("version + 1")}
+	if task.ChecklistAutoComplete && task.RRule == "" {
+		var progress models.ChecklistProgress
+		err := tx.Model(&models.ChecklistItem{}).
+			Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN checked THEN 1 ELSE 0 END), 0) AS checked").
+			Where("task_id = ?", taskID).
+			Scan(&progress).Error
+		if err != nil {
+			return err
+		}
+		if done := progress.Checked == progress.Total; progress.Total > 0 && done != task.Done {
+			updates["done"] = done
+			updates["completed_at"] = completionUpdate(done)
+		}
+	}
+
+	wasDone := task.Done
+	if err := tx.Model(&task).Updates(updates).Error; err != nil {
+		return err
+	}
+	done, changed := updates["done"]
+	if !changed {
+		return nil
+	}
+	if err := recordTaskEvent(tx, task.UserID, task.ID, models.TaskUpdated, change("done", wasDone, done)); err != nil {
+		return err
+	}
+	return touchParents(tx, task.ParentID)
+}
+
+// bumpTask bumps the version of a task whose details have changed.
+func bumpTask(tx *gorm.DB, id uint) error {
+	return tx.Model(&models.Task{}).Where("id = ?", id).
+		Update("version", gorm.Expr("version + 1")).Error
+}
+
+type GormUserStore struct {
+	gormConn
+}
+
+func (s *GormUserStore) Create(ctx context.Context, user *models.User) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return translateError(db.Create(user).Error)
+}
+
+func (s *GormUserStore) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var user models.User
+	err := db.Where("email = ?", email).First(&user).Error
+	return user, translateError(err)
+}
+
+type GormRefreshTokenStore struct {
+	gormConn
+}
+
+func (s *GormRefreshTokenStore) Create(ctx context.Context, token *models.RefreshToken) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return translateError(db.Create(token).Error)
+}
+
+func (s *GormRefreshTokenStore) GetByHash(ctx context.Context, hash string) (models.RefreshToken, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	var token models.RefreshToken
+	err := db.Where("token_hash = ?", hash).First(&token).Error
+	return token, translateError(err)
+}
+
+func (s *GormRefreshTokenStore) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	result := db.Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (s *GormRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	db, cancel := s.session(ctx)
+	defer cancel()
+
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+// Ping checks that the database answers.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,1454 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/rank"
+
+	"gorm.io/gorm"
+)
+
+// NewMemoryStores returns stores that keep everything in process memory.
+// They are meant for tests and for running the API without a database.
+func NewMemoryStores() Stores {
+	tasks := &MemoryTaskStore{
+		tasks:       map[uint]models.Task{},
+		trash:       map[uint]models.Task{},
+		tags:        map[uint]models.Tag{},
+		comments:    map[uint]models.Comment{},
+		attachments: map[uint]models.Attachment{},
+		checklist:   map[uint]models.ChecklistItem{},
+	}
+	return Stores{
+		Tasks:         tasks,
+		Projects:      &MemoryProjectStore{projects: map[uint]models.Project{}, tasks: tasks},
+		Tags:          &MemoryTagStore{tasks: tasks},
+		Comments:      &MemoryCommentStore{tasks: tasks},
+		Attachments:   &MemoryAttachmentStore{tasks: tasks},
+		Checklists:    &MemoryChecklistStore{tasks: tasks},
+		Users:         &MemoryUserStore{users: map[uint]models.User{}},
+		RefreshTokens: &MemoryRefreshTokenStore{tokens: map[uint]models.RefreshToken{}},
+	}
+}
+
+// MemoryTaskStore also holds the tags, which tasks create by name, and the
+// comments, attachments and checklist items, which go with their tasks, so
+// all are guarded by the same lock. Trashed tasks are kept apart from the live ones.
+type MemoryTaskStore struct {
+	mu               sync.RWMutex
+	nextID           uint
+	tasks            map[uint]models.Task
+	trash            map[uint]models.Task
+	nextTagID        uint
+	tags             map[uint]models.Tag
+	nextOccurrenceID uint
+	occurrences      []models.TaskOccurrence
+	nextEventID      uint
+	events           []models.TaskEvent
+	nextCommentID    uint
+	comments         map[uint]models.Comment
+	nextAttachmentID uint
+	attachments      map[uint]models.Attachment
+	orphanedBlobs    []string
+	nextChecklistID  uint
+	checklist        map[uint]models.ChecklistItem
+}
+
+func (s *MemoryTaskStore) List(ctx context.Context, userID uint, opts TaskListOptions) (TaskList, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := TaskList{Tasks: []models.Task{}}
+
+	var matched []models.Task
+	for _, task := range s.tasks {
+		if task.UserID == userID && matchesTaskFilter(task, opts.Filter) {
+			matched = append(matched, task)
+		}
+	}
+	list.Total = int64(len(matched))
+
+	sortKey := opts.Sort
+	if sortKey == "" {
+		sortKey = "id"
+	}
+	if _, ok := TaskSortFields[sortKey]; !ok {
+		return list, ErrInvalidCursor
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareTasks(matched[i], matched[j], sortKey, opts.Desc) < 0
+	})
+
+	if opts.After != nil {
+		after, err := cursorTask(sortKey, *opts.After)
+		if err != nil {
+			return list, err
+		}
+
+		start := sort.Search(len(matched), func(i int) bool {
+			return compareTasks(matched[i], after, sortKey, opts.Desc) > 0
+		})
+		matched = matched[start:]
+	}
+
+	if opts.Offset >= len(matched) {
+		return list, nil
+	}
+	matched = matched[opts.Offset:]
+
+	if opts.Limit > 0 && len(matched) > opts.Limit {
+		matched = matched[:opts.Limit]
+		list.HasMore = true
+	}
+
+	for _, task := range matched {
+		list.Tasks = append(list.Tasks, s.withDetails(task))
+	}
+	return list, nil
+}
+
+// copyTask returns task with its own copy of the tags, so callers can't
+// modify the stored slice.
+func copyTask(task models.Task) models.Task {
+	task.Tags = append([]string{}, task.Tags...)
+	return task
+}
+
+// withDetails returns a copy of task with its subtask and checklist
+// progress and comment count filled in. The caller must hold the lock.
+func (s *MemoryTaskStore) withDetails(task models.Task) models.Task {
+	task = copyTask(task)
+	task.Subtasks = s.subtaskProgress(task.ID)
+	task.Checklist = s.checklistProgress(task.ID)
+	task.CommentCount = 0
+	for _, comment := range s.comments {
+		if comment.TaskID == task.ID {
+			task.CommentCount++
+		}
+	}
+	return task
+}
+
+func (s *MemoryTaskStore) subtaskProgress(parentID uint) models.SubtaskProgress {
+	var progress models.SubtaskProgress
+	for _, task := range s.tasks {
+		if task.ParentID != nil && *task.ParentID == parentID {
+			progress.Total++
+			if task.Done {
+				progress.Done++
+			}
+		}
+	}
+	return progress
+}
+
+func (s *MemoryTaskStore) checklistProgress(taskID uint) models.ChecklistProgress {
+	var progress models.ChecklistProgress
+	for _, item := range s.checklist {
+		if item.TaskID == taskID {
+			progress.Total++
+			if item.Checked {
+				progress.Checked++
+			}
+		}
+	}
+	return progress
+}
+
+// touchParents bumps the version of parentID and syncs auto-completing
+// parents with their subtasks, like the gorm store. The caller must hold
+// the write lock.
+func (s *MemoryTaskStore) touchParents(ctx context.Context, parentID *uint) {
+	for parentID != nil {
+		parent, ok := s.tasks[*parentID]
+		if !ok {
+			return
+		}
+
+		bump(&parent)
+		changed := false
+		if parent.AutoComplete && parent.RRule == "" {
+			progress := s.subtaskProgress(parent.ID)
+			if done := progress.Done == progress.Total; progress.Total > 0 && done != parent.Done {
+				s.record(ctx, parent.UserID, parent.ID, models.TaskUpdated, change("done", parent.Done, done))
+				parent.Done = done
+				parent.CompletedAt = completionUpdate(done)
+				changed = true
+			}
+		}
+
+		s.tasks[parent.ID] = parent
+		if !changed {
+			return
+		}
+		parentID = parent.ParentID
+	}
+}
+
+// record adds an event to the history of one of userID's tasks, like
+// recordTaskEvent in the gorm store. The caller must hold the write lock.
+func (s *MemoryTaskStore) record(ctx context.Context, userID, taskID uint, action string, changes models.TaskChanges) {
+	s.nextEventID++
+	event := newTaskEvent(ctx, userID, taskID, action, changes)
+	event.ID = s.nextEventID
+	s.events = append(s.events, event)
+}
+
+// bump records a change to a stored task, like the version and updated_at
+// updates of the gorm store.
+func bump(task *models.Task) {
+	task.Version++
+	task.UpdatedAt = time.Now().UTC()
+}
+
+// subtreeIDs returns id followed by the ids of its descendants. The caller
+// must hold the lock.
+func (s *MemoryTaskStore) subtreeIDs(id uint) []uint {
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		for _, task := range s.tasks {
+			if task.ParentID != nil && *task.ParentID == ids[i] && !slices.Contains(ids, task.ID) {
+				ids = append(ids, task.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// attachTags normalises task.Tags and creates the tags the user doesn't
+// have yet. The caller must hold the write lock.
+func (s *MemoryTaskStore) attachTags(task *models.Task) {
+	task.Tags = uniqueTagNames(task.Tags)
+
+	for _, name := range task.Tags {
+		if _, ok := s.findTag(task.UserID, name); !ok {
+			s.nextTagID++
+			s.tags[s.nextTagID] = models.Tag{ID: s.nextTagID, Name: name, UserID: task.UserID}
+		}
+	}
+}
+
+func (s *MemoryTaskStore) findTag(userID uint, name string) (models.Tag, bool) {
+	for _, tag := range s.tags {
+		if tag.UserID == userID && tag.Name == name {
+			return tag, true
+		}
+	}
+	return models.Tag{}, false
+}
+
+func matchesTaskFilter(task models.Task, filter TaskFilter) bool {
+	if filter.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *filter.ProjectID) {
+		return false
+	}
+
+	if filter.InboxOnly && task.ProjectID != nil {
+		return false
+	}
+
+	if filter.ParentID != nil && (task.ParentID == nil || *task.ParentID != *filter.ParentID) {
+		return false
+	}
+
+	if filter.RootOnly && task.ParentID != nil {
+		return false
+	}
+
+	if len(filter.Tags) > 0 {
+		matched := 0
+		for _, name := range uniqueTagNames(filter.Tags) {
+			if slices.Contains(task.Tags, name) {
+				matched++
+			}
+		}
+		if matched == 0 || (filter.AllTags && matched < len(uniqueTagNames(filter.Tags))) {
+			return false
+		}
+	}
+
+	if filter.DueBefore != nil && (task.DueAt == nil || !task.DueAt.Before(*filter.DueBefore)) {
+		return false
+	}
+
+	if filter.DueAfter != nil && (task.DueAt == nil || !task.DueAt.After(*filter.DueAfter)) {
+		return false
+	}
+
+	if filter.Overdue != nil {
+		overdue := !task.Done && task.DueAt != nil && task.DueAt.Before(filter.Now)
+		if overdue != *filter.Overdue {
+			return false
+		}
+	}
+
+	if filter.Done != nil && task.Done != *filter.Done {
+		return false
+	}
+
+	if filter.UpdatedSince != nil && task.UpdatedAt.Before(*filter.UpdatedSince) {
+		return false
+	}
+
+	if filter.Title != "" && !strings.Contains(strings.ToLower(task.Title), strings.ToLower(filter.Title)) {
+		return false
+	}
+
+	return true
+}
+
+// compareTasks orders tasks the same way as the SQL stores: by the sort
+// column with NULLs last in both directions, then by id.
+func compareTasks(a, b models.Task, sortKey string, desc bool) int {
+	av, bv := TaskSortValue(a, sortKey), TaskSortValue(b, sortKey)
+
+	switch {
+	case av == nil && bv != nil:
+		return 1
+	case av != nil && bv == nil:
+		return -1
+	}
+
+	cmp := 0
+	if av != nil && sortKey != "id" {
+		cmp = compareSortValues(sortKey, *av, *bv)
+	}
+	if cmp == 0 {
+		cmp = compareUint(a.ID, b.ID)
+	}
+
+	if desc {
+		return -cmp
+	}
+	return cmp
+}
+
+func compareSortValues(sortKey, a, b string) int {
+	switch TaskSortFields[sortKey] {
+	case SortUint:
+		an, _ := strconv.ParseUint(a, 10, 64)
+		bn, _ := strconv.ParseUint(b, 10, 64)
+		return cmp.Compare(an, bn)
+	case SortBool:
+		return compareBool(a == "true", b == "true")
+	case SortTime:
+		at, _ := time.Parse(time.RFC3339Nano, a)
+		bt, _ := time.Parse(time.RFC3339Nano, b)
+		return at.Compare(bt)
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareUint(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case !a:
+		return -1
+	}
+	return 1
+}
+
+// cursorTask builds a placeholder task positioned at the cursor so it can be
+// compared with compareTasks.
+func cursorTask(sortKey string, cursor TaskCursor) (models.Task, error) {
+	task := models.Task{ID: cursor.ID}
+	if cursor.Value == nil || sortKey == "id" {
+		return task, nil
+	}
+
+	value, err := parseSortValue(sortKey, *cursor.Value)
+	if err != nil {
+		return task, err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		task.Done = v
+	case uint:
+		task.Priority = int(v)
+	case time.Time:
+		switch sortKey {
+		case "start_at":
+			task.StartAt = &v
+		case "due_at":
+			task.DueAt = &v
+		case "created_at":
+			task.CreatedAt = v
+		case "updated_at":
+			task.UpdatedAt = v
+		case "completed_at":
+			task.CompletedAt = &v
+		}
+	case string:
+		if sortKey == "position" {
+			task.Position = v
+		} else {
+			task.Title = v
+		}
+	}
+
+	return task, nil
+}
+
+func (s *MemoryTaskStore) Get(ctx context.Context, userID, id uint) (models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok || task.UserID != userID {
+		return models.Task{}, ErrNotFound
+	}
+	return s.withDetails(task), nil
+}
+
+func (s *MemoryTaskStore) Subtree(ctx context.Context, userID, id uint) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if task, ok := s.tasks[id]; !ok || task.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	ids := s.subtreeIDs(id)
+	slices.Sort(ids)
+
+	tasks := make([]models.Task, 0, len(ids))
+	for _, id := range ids {
+		tasks = append(tasks, s.withDetails(s.tasks[id]))
+	}
+	return tasks, nil
+}
+
+func (s *MemoryTaskStore) Create(ctx context.Context, task *models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	task.ID = s.nextID
+	if task.Version == 0 {
+		task.Version = 1
+	}
+	if task.Position == "" {
+		// Trashed tasks keep their position, so they count too.
+		last := ""
+		for _, tasks := range []map[uint]models.Task{s.tasks, s.trash} {
+			for _, existing := range tasks {
+				if existing.UserID == task.UserID && existing.Position > last {
+					last = existing.Position
+				}
+			}
+		}
+		task.Position = rank.Between(last, "")
+	}
+	now := time.Now().UTC()
+	task.CreatedAt, task.UpdatedAt = now, now
+	trackCompletion(task, nil)
+	s.attachTags(task)
+	s.tasks[task.ID] = copyTask(*task)
+	s.record(ctx, task.UserID, task.ID, models.TaskCreated, diffTask(nil, *task))
+	s.touchParents(ctx, task.ParentID)
+	return nil
+}
+
+func (s *MemoryTaskStore) Update(ctx context.Context, task *models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(ctx, task)
+}
+
+func (s *MemoryTaskStore) Move(ctx context.Context, task *models.Task, anchorID uint, after bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	anchor, ok := s.tasks[anchorID]
+	if !ok || anchor.UserID != task.UserID {
+		return ErrNotFound
+	}
+
+	// Find the neighbour on the other side of the anchor, in the order
+	// used when sorting by position. Trashed tasks keep their position, so
+	// they count too.
+	var neighbor *models.Task
+	for _, tasks := range []map[uint]models.Task{s.tasks, s.trash} {
+		for _, other := range tasks {
+			if other.UserID != task.UserID || other.ID == task.ID || other.ID == anchor.ID {
+				continue
+			}
+
+			c := compareTasks(other, anchor, "position", false)
+			if after && c > 0 && (neighbor == nil || compareTasks(other, *neighbor, "position", false) < 0) ||
+				!after && c < 0 && (neighbor == nil || compareTasks(other, *neighbor, "position", false) > 0) {
+				neighbor = &other
+			}
+		}
+	}
+
+	stored, ok := s.tasks[task.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	var neighborPosition *string
+	if neighbor != nil {
+		neighborPosition = &neighbor.Position
+	}
+	task.Position = positionNextTo(anchor.Position, neighborPosition, after)
+	task.UpdatedAt = time.Now().UTC()
+	s.record(ctx, task.UserID, task.ID, models.TaskUpdated, change("position", stored.Position, task.Position))
+	stored.Position = task.Position
+	stored.Version = task.Version
+	stored.UpdatedAt = task.UpdatedAt
+	s.tasks[task.ID] = stored
+	return nil
+}
+
+func (s *MemoryTaskStore) CompleteOccurrence(ctx context.Context, task *models.Task, occurrence *models.TaskOccurrence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.update(ctx, task); err != nil {
+		return err
+	}
+
+	s.nextOccurrenceID++
+	occurrence.ID = s.nextOccurrenceID
+	occurrence.TaskID = task.ID
+	s.occurrences = append(s.occurrences, *occurrence)
+	return nil
+}
+
+func (s *MemoryTaskStore) Occurrences(ctx context.Context, userID, taskID uint) ([]models.TaskOccurrence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	occurrences := []models.TaskOccurrence{}
+	if task, ok := s.tasks[taskID]; !ok || task.UserID != userID {
+		return occurrences, nil
+	}
+
+	for i := len(s.occurrences) - 1; i >= 0; i-- {
+		if s.occurrences[i].TaskID == taskID {
+			occurrences = append(occurrences, s.occurrences[i])
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].CompletedAt.After(occurrences[j].CompletedAt)
+	})
+	return occurrences, nil
+}
+
+func (s *MemoryTaskStore) History(ctx context.Context, userID, taskID uint) ([]models.TaskEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []models.TaskEvent{}
+	for _, event := range s.events {
+		if event.TaskID == taskID && event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// update saves task. The caller must hold the write lock.
+func (s *MemoryTaskStore) update(ctx context.Context, task *models.Task) error {
+	old, ok := s.tasks[task.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	task.CreatedAt = old.CreatedAt
+	task.UpdatedAt = time.Now().UTC()
+	trackCompletion(task, &old)
+	s.attachTags(task)
+	s.tasks[task.ID] = copyTask(*task)
+	if changes := diffTask(&old, *task); len(changes) > 0 {
+		s.record(ctx, task.UserID, task.ID, models.TaskUpdated, changes)
+	}
+
+	moved := !equalIDs(old.ParentID, task.ParentID)
+	if moved || old.Done != task.Done {
+		s.touchParents(ctx, old.ParentID)
+	}
+	if moved {
+		s.touchParents(ctx, task.ParentID)
+	}
+	return nil
+}
+
+func (s *MemoryTaskStore) Delete(ctx context.Context, task *models.Task, deleteSubtasks bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	ids := []uint{task.ID}
+	if deleteSubtasks {
+		ids = s.subtreeIDs(task.ID)
+	} else {
+		for id, child := range s.tasks {
+			if child.ParentID != nil && *child.ParentID == task.ID {
+				s.record(ctx, task.UserID, id, models.TaskUpdated, change("parent_id", task.ID, task.ParentID))
+				child.ParentID = task.ParentID
+				bump(&child)
+				s.tasks[id] = child
+			}
+		}
+	}
+
+	for _, id := range ids {
+		s.moveToTrash(id, now)
+		s.record(ctx, task.UserID, id, models.TaskDeleted, nil)
+	}
+
+	s.touchParents(ctx, task.ParentID)
+	return nil
+}
+
+// moveToTrash marks a live task as deleted at the given time. The caller
+// must hold the write lock.
+func (s *MemoryTaskStore) moveToTrash(id uint, at time.Time) {
+	task := s.tasks[id]
+	task.DeletedAt = gorm.DeletedAt{Time: at, Valid: true}
+	s.trash[id] = task
+	delete(s.tasks, id)
+}
+
+// trashedSubtreeIDs returns the trashed task id followed by the descendants
+// trashed along with it. The caller must hold the lock.
+func (s *MemoryTaskStore) trashedSubtreeIDs(id uint) []uint {
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		parent := s.trash[ids[i]]
+		for _, task := range s.trash {
+			if task.ParentID != nil && *task.ParentID == parent.ID &&
+				task.DeletedAt.Time.Equal(parent.DeletedAt.Time) && !slices.Contains(ids, task.ID) {
+				ids = append(ids, task.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func (s *MemoryTaskStore) Trash(ctx context.Context, userID uint) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := []models.Task{}
+	for _, task := range s.trash {
+		if task.UserID == userID {
+			tasks = append(tasks, s.withDetails(task))
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if c := tasks[i].DeletedAt.Time.Compare(tasks[j].DeletedAt.Time); c != 0 {
+			return c > 0
+		}
+		return tasks[i].ID > tasks[j].ID
+	})
+	return tasks, nil
+}
+
+func (s *MemoryTaskStore) GetTrashed(ctx context.Context, userID, id uint) (models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.trash[id]
+	if !ok || task.UserID != userID {
+		return models.Task{}, ErrNotFound
+	}
+	return s.withDetails(task), nil
+}
+
+func (s *MemoryTaskStore) Restore(ctx context.Context, task *models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.trash[task.ID]; !ok || stored.UserID != task.UserID {
+		return ErrNotFound
+	}
+
+	var detached models.TaskChanges
+	if task.ParentID != nil {
+		if _, ok := s.tasks[*task.ParentID]; !ok {
+			detached = change("parent_id", *task.ParentID, nil)
+			task.ParentID = nil
+		}
+	}
+
+	for _, id := range s.trashedSubtreeIDs(task.ID) {
+		restored := s.trash[id]
+		var changes models.TaskChanges
+		if id == task.ID {
+			restored.ParentID = task.ParentID
+			changes = detached
+		}
+		restored.DeletedAt = gorm.DeletedAt{}
+		bump(&restored)
+		s.tasks[id] = restored
+		delete(s.trash, id)
+		s.record(ctx, task.UserID, id, models.TaskRestored, changes)
+	}
+
+	s.touchParents(ctx, task.ParentID)
+	return nil
+}
+
+func (s *MemoryTaskStore) Purge(ctx context.Context, task *models.Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.trash[task.ID]; !ok || stored.UserID != task.UserID {
+		return ErrNotFound
+	}
+
+	s.purge(ctx, s.trashedSubtreeIDs(task.ID))
+	return nil
+}
+
+func (s *MemoryTaskStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uint
+	for id, task := range s.trash {
+		if task.DeletedAt.Time.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+
+	s.purge(ctx, ids)
+	return int64(len(ids)), nil
+}
+
+// purge permanently deletes trashed tasks with their occurrences,
+// comments, attachments and checklists, and records it in their history,
+// like purgeTasks in the gorm store. The caller must hold the write lock.
+func (s *MemoryTaskStore) purge(ctx context.Context, ids []uint) {
+	for _, id := range ids {
+		s.record(ctx, s.trash[id].UserID, id, models.TaskPurged, nil)
+		delete(s.trash, id)
+	}
+
+	for id, task := range s.trash {
+		if task.ParentID != nil && slices.Contains(ids, *task.ParentID) {
+			task.ParentID = nil
+			s.trash[id] = task
+		}
+	}
+
+	s.occurrences = slices.DeleteFunc(s.occurrences, func(occurrence models.TaskOccurrence) bool {
+		return slices.Contains(ids, occurrence.TaskID)
+	})
+	for id, comment := range s.comments {
+		if slices.Contains(ids, comment.TaskID) {
+			delete(s.comments, id)
+		}
+	}
+	for id, attachment := range s.attachments {
+		if slices.Contains(ids, attachment.TaskID) {
+			s.orphanedBlobs = append(s.orphanedBlobs, attachment.Key)
+			delete(s.attachments, id)
+		}
+	}
+	for id, item := range s.checklist {
+		if slices.Contains(ids, item.TaskID) {
+			delete(s.checklist, id)
+		}
+	}
+}
+
+type MemoryProjectStore struct {
+	mu       sync.RWMutex
+	nextID   uint
+	projects map[uint]models.Project
+	// tasks is updated when a project is deleted.
+	tasks *MemoryTaskStore
+}
+
+func (s *MemoryProjectStore) List(ctx context.Context, userID uint, includeArchived bool) ([]models.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := []models.Project{}
+	for _, project := range s.projects {
+		if project.UserID == userID && (includeArchived || !project.Archived) {
+			projects = append(projects, project)
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Position != projects[j].Position {
+			return projects[i].Position < projects[j].Position
+		}
+		return projects[i].ID < projects[j].ID
+	})
+
+	return projects, nil
+}
+
+func (s *MemoryProjectStore) Get(ctx context.Context, userID, id uint) (models.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	project, ok := s.projects[id]
+	if !ok || project.UserID != userID {
+		return models.Project{}, ErrNotFound
+	}
+	return project, nil
+}
+
+func (s *MemoryProjectStore) Create(ctx context.Context, project *models.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if project.Position == 0 {
+		for _, existing := range s.projects {
+			if existing.UserID == project.UserID && existing.Position > project.Position {
+				project.Position = existing.Position
+			}
+		}
+		project.Position++
+	}
+
+	s.nextID++
+	project.ID = s.nextID
+	s.projects[project.ID] = *project
+	return nil
+}
+
+func (s *MemoryProjectStore) Update(ctx context.Context, project *models.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[project.ID]; !ok {
+		return ErrNotFound
+	}
+	s.projects[project.ID] = *project
+	return nil
+}
+
+func (s *MemoryProjectStore) Delete(ctx context.Context, project *models.Project, deleteTasks bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	now := time.Now().UTC()
+	parentIDs := map[uint]bool{}
+	for id, task := range s.tasks.tasks {
+		if task.UserID != project.UserID || task.ProjectID == nil || *task.ProjectID != project.ID {
+			continue
+		}
+
+		task.ProjectID = nil
+		bump(&task)
+		s.tasks.tasks[id] = task
+
+		if deleteTasks {
+			s.tasks.moveToTrash(id, now)
+			s.tasks.record(ctx, project.UserID, id, models.TaskDeleted, nil)
+			s.detachSubtasks(ctx, id, project.ID)
+			if task.ParentID != nil {
+				parentIDs[*task.ParentID] = true
+			}
+		} else {
+			s.tasks.record(ctx, project.UserID, id, models.TaskUpdated, change("project_id", project.ID, nil))
+		}
+	}
+
+	// Parents outside the project lose subtasks. Parents that were deleted
+	// along with the project are no longer found and are skipped.
+	for parentID := range parentIDs {
+		s.tasks.touchParents(ctx, &parentID)
+	}
+
+	// Trashed tasks move to the Inbox so that none refers to the project.
+	for id, task := range s.tasks.trash {
+		if task.ProjectID != nil && *task.ProjectID == project.ID {
+			task.ProjectID = nil
+			bump(&task)
+			s.tasks.trash[id] = task
+		}
+	}
+
+	delete(s.projects, project.ID)
+	return nil
+}
+
+// MemoryTagStore works on the tags held by its MemoryTaskStore.
+type MemoryTagStore struct {
+	tasks *MemoryTaskStore
+}
+
+func (s *MemoryTagStore) List(ctx context.Context, userID uint) ([]models.Tag, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	tags := []models.Tag{}
+	for _, tag := range s.tasks.tags {
+		if tag.UserID == userID {
+			tags = append(tags, s.withTaskCount(tag))
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (s *MemoryTagStore) withTaskCount(tag models.Tag) models.Tag {
+	tag.TaskCount = 0
+	for _, task := range s.tasks.tasks {
+		if task.UserID == tag.UserID && slices.Contains(task.Tags, tag.Name) {
+			tag.TaskCount++
+		}
+	}
+	return tag
+}
+
+func (s *MemoryTagStore) Get(ctx context.Context, userID, id uint) (models.Tag, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	tag, ok := s.tasks.tags[id]
+	if !ok || tag.UserID != userID {
+		return models.Tag{}, ErrNotFound
+	}
+	return s.withTaskCount(tag), nil
+}
+
+func (s *MemoryTagStore) Create(ctx context.Context, tag *models.Tag) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	if _, ok := s.tasks.findTag(tag.UserID, tag.Name); ok {
+		return ErrDuplicate
+	}
+
+	s.tasks.nextTagID++
+	tag.ID = s.tasks.nextTagID
+	s.tasks.tags[tag.ID] = *tag
+	return nil
+}
+
+func (s *MemoryTagStore) Update(ctx context.Context, tag *models.Tag) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	old, ok := s.tasks.tags[tag.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if existing, ok := s.tasks.findTag(tag.UserID, tag.Name); ok && existing.ID != tag.ID {
+		return ErrDuplicate
+	}
+
+	s.retag(ctx, old, &tag.Name)
+	s.tasks.tags[tag.ID] = *tag
+	return nil
+}
+
+func (s *MemoryTagStore) Merge(ctx context.Context, source, target *models.Tag) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	s.retag(ctx, *source, &target.Name)
+	delete(s.tasks.tags, source.ID)
+	return nil
+}
+
+func (s *MemoryTagStore) Delete(ctx context.Context, tag *models.Tag) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	s.retag(ctx, *tag, nil)
+	delete(s.tasks.tags, tag.ID)
+	return nil
+}
+
+// retag replaces tag on its tasks, trashed ones included, with the tag named
+// to, or removes it when to is nil, bumping the tasks' versions and recording
+// the change in their history.
+func (s *MemoryTagStore) retag(ctx context.Context, tag models.Tag, to *string) {
+	for _, tasks := range []map[uint]models.Task{s.tasks.tasks, s.tasks.trash} {
+		for id, task := range tasks {
+			if task.UserID != tag.UserID || !slices.Contains(task.Tags, tag.Name) {
+				continue
+			}
+
+			tags := slices.DeleteFunc(copyTask(task).Tags, func(name string) bool {
+				return name == tag.Name
+			})
+			if to != nil {
+				tags = append(tags, *to)
+			}
+
+			s.tasks.record(ctx, task.UserID, id, models.TaskUpdated, change("tags", uniqueTagNames(task.Tags), uniqueTagNames(tags)))
+			task.Tags = uniqueTagNames(tags)
+			bump(&task)
+			tasks[id] = task
+		}
+	}
+}
+
+// detachSubtasks clears the parent of the subtasks of parentID that are
+// outside the project being deleted. The caller must hold the task lock.
+func (s *MemoryProjectStore) detachSubtasks(ctx context.Context, parentID, projectID uint) {
+	for id, task := range s.tasks.tasks {
+		if task.ParentID == nil || *task.ParentID != parentID {
+			continue
+		}
+		if task.ProjectID != nil && *task.ProjectID == projectID {
+			continue
+		}
+
+		s.tasks.record(ctx, task.UserID, id, models.TaskUpdated, change("parent_id", parentID, nil))
+		task.ParentID = nil
+		bump(&task)
+		s.tasks.tasks[id] = task
+	}
+}
+
+// MemoryCommentStore works on the comments held by its MemoryTaskStore.
+type MemoryCommentStore struct {
+	tasks *MemoryTaskStore
+}
+
+func (s *MemoryCommentStore) List(ctx context.Context, taskID uint, limit, offset int) (CommentList, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	comments := []models.Comment{}
+	for _, comment := range s.tasks.comments {
+		if comment.TaskID == taskID {
+			comments = append(comments, comment)
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		if c := comments[i].CreatedAt.Compare(comments[j].CreatedAt); c != 0 {
+			return c < 0
+		}
+		return comments[i].ID < comments[j].ID
+	})
+
+	list := CommentList{Total: int64(len(comments))}
+	comments = comments[min(offset, len(comments)):]
+	if limit > 0 && len(comments) > limit {
+		comments = comments[:limit]
+	}
+	list.Comments = comments
+	return list, nil
+}
+
+func (s *MemoryCommentStore) Get(ctx context.Context, taskID, id uint) (models.Comment, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	comment, ok := s.tasks.comments[id]
+	if !ok || comment.TaskID != taskID {
+		return models.Comment{}, ErrNotFound
+	}
+	return comment, nil
+}
+
+func (s *MemoryCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	s.tasks.nextCommentID++
+	comment.ID = s.tasks.nextCommentID
+	now := time.Now().UTC()
+	comment.CreatedAt, comment.UpdatedAt = now, now
+	s.tasks.comments[comment.ID] = *comment
+	s.bumpTask(comment.TaskID)
+	return nil
+}
+
+func (s *MemoryCommentStore) Update(ctx context.Context, comment *models.Comment) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	stored, ok := s.tasks.comments[comment.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	comment.UpdatedAt = time.Now().UTC()
+	stored.Body = comment.Body
+	stored.UpdatedAt = comment.UpdatedAt
+	s.tasks.comments[comment.ID] = stored
+	return nil
+}
+
+func (s *MemoryCommentStore) Delete(ctx context.Context, comment *models.Comment) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	if _, ok := s.tasks.comments[comment.ID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.tasks.comments, comment.ID)
+	s.bumpTask(comment.TaskID)
+	return nil
+}
+
+// bumpTask bumps the version of a task whose comment count has changed.
+// The caller must hold the write lock.
+func (s *MemoryCommentStore) bumpTask(id uint) {
+	if task, ok := s.tasks.tasks[id]; ok {
+		bump(&task)
+		s.tasks.tasks[id] = task
+	}
+}
+
+// MemoryAttachmentStore works on the attachments held by its
+// MemoryTaskStore.
+type MemoryAttachmentStore struct {
+	tasks *MemoryTaskStore
+}
+
+func (s *MemoryAttachmentStore) List(ctx context.Context, taskID uint) ([]models.Attachment, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	attachments := []models.Attachment{}
+	for _, attachment := range s.tasks.attachments {
+		if attachment.TaskID == taskID {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	sort.Slice(attachments, func(i, j int) bool {
+		if c := attachments[i].CreatedAt.Compare(attachments[j].CreatedAt); c != 0 {
+			return c < 0
+		}
+		return attachments[i].ID < attachments[j].ID
+	})
+	return attachments, nil
+}
+
+func (s *MemoryAttachmentStore) Get(ctx context.Context, taskID, id uint) (models.Attachment, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	attachment, ok := s.tasks.attachments[id]
+	if !ok || attachment.TaskID != taskID {
+		return models.Attachment{}, ErrNotFound
+	}
+	return attachment, nil
+}
+
+func (s *MemoryAttachmentStore) Create(ctx context.Context, attachment *models.Attachment) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	s.tasks.nextAttachmentID++
+	attachment.ID = s.tasks.nextAttachmentID
+	attachment.CreatedAt = time.Now().UTC()
+	s.tasks.attachments[attachment.ID] = *attachment
+	return nil
+}
+
+func (s *MemoryAttachmentStore) Delete(ctx context.Context, attachment *models.Attachment) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	stored, ok := s.tasks.attachments[attachment.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.tasks.attachments, attachment.ID)
+	s.tasks.orphanedBlobs = append(s.tasks.orphanedBlobs, stored.Key)
+	return nil
+}
+
+func (s *MemoryAttachmentStore) OrphanedBlobs(ctx context.Context, limit int) ([]string, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	keys := s.tasks.orphanedBlobs[:min(limit, len(s.tasks.orphanedBlobs))]
+	return slices.Clone(keys), nil
+}
+
+func (s *MemoryAttachmentStore) ForgetBlobs(ctx context.Context, keys []string) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	s.tasks.orphanedBlobs = slices.DeleteFunc(s.tasks.orphanedBlobs, func(key string) bool {
+		return slices.Contains(keys, key)
+	})
+	return nil
+}
+
+// MemoryChecklistStore works on the checklist items held by its
+// MemoryTaskStore.
+type MemoryChecklistStore struct {
+	tasks *MemoryTaskStore
+}
+
+func (s *MemoryChecklistStore) List(ctx context.Context, taskID uint) ([]models.ChecklistItem, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	return s.list(taskID), nil
+}
+
+// list returns the checklist of a task in position order. The caller must
+// hold the lock.
+func (s *MemoryChecklistStore) list(taskID uint) []models.ChecklistItem {
+	items := []models.ChecklistItem{}
+	for _, item := range s.tasks.checklist {
+		if item.TaskID == taskID {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Position != items[j].Position {
+			return items[i].Position < items[j].Position
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+func (s *MemoryChecklistStore) Get(ctx context.Context, taskID, id uint) (models.ChecklistItem, error) {
+	s.tasks.mu.RLock()
+	defer s.tasks.mu.RUnlock()
+
+	item, ok := s.tasks.checklist[id]
+	if !ok || item.TaskID != taskID {
+		return models.ChecklistItem{}, ErrNotFound
+	}
+	return item, nil
+}
+
+func (s *MemoryChecklistStore) Create(ctx context.Context, item *models.ChecklistItem) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	item.Position = 1
+	for _, other := range s.tasks.checklist {
+		if other.TaskID == item.TaskID && other.Position >= item.Position {
+			item.Position = other.Position + 1
+		}
+	}
+
+	s.tasks.nextChecklistID++
+	item.ID = s.tasks.nextChecklistID
+	now := time.Now().UTC()
+	item.CreatedAt, item.UpdatedAt = now, now
+	s.tasks.checklist[item.ID] = *item
+	s.touchChecklist(ctx, item.TaskID)
+	return nil
+}
+
+func (s *MemoryChecklistStore) Update(ctx context.Context, item *models.ChecklistItem) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	stored, ok := s.tasks.checklist[item.ID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	item.UpdatedAt = time.Now().UTC()
+	stored.Text = item.Text
+	stored.Checked = item.Checked
+	stored.UpdatedAt = item.UpdatedAt
+	s.tasks.checklist[item.ID] = stored
+	s.touchChecklist(ctx, item.TaskID)
+	return nil
+}
+
+func (s *MemoryChecklistStore) Delete(ctx context.Context, item *models.ChecklistItem) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	if _, ok := s.tasks.checklist[item.ID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.tasks.checklist, item.ID)
+	s.touchChecklist(ctx, item.TaskID)
+	return nil
+}
+
+func (s *MemoryChecklistStore) Reorder(ctx context.Context, taskID uint, ids []uint) error {
+	s.tasks.mu.Lock()
+	defer s.tasks.mu.Unlock()
+
+	var current []uint
+	for _, item := range s.list(taskID) {
+		current = append(current, item.ID)
+	}
+	if !samePermutation(current, ids) {
+		return ErrInvalidOrder
+	}
+
+	now := time.Now().UTC()
+	for i, id := range ids {
+		item := s.tasks.checklist[id]
+		item.Position = i + 1
+		item.UpdatedAt = now
+		s.tasks.checklist[id] = item
+	}
+
+	if task, ok := s.tasks.tasks[taskID]; ok {
+		bump(&task)
+		s.tasks.tasks[taskID] = task
+	}
+	return nil
+}
+
+// touchChecklist bumps the version of a task whose checklist has changed
+// and syncs its Done flag, like the gorm store. The caller must hold the
+// write lock.
+func (s *MemoryChecklistStore) touchChecklist(ctx context.Context, taskID uint) {
+	task, ok := s.tasks.tasks[taskID]
+	if !ok {
+		return
+	}
+
+	bump(&task)
+	changed := false
+	if task.ChecklistAutoComplete && task.RRule == "" {
+		progress := s.tasks.checklistProgress(task.ID)
+		if done := progress.Checked == progress.Total; progress.Total > 0 && done != task.Done {
+			s.tasks.record(ctx, task.UserID, task.ID, models.TaskUpdated, change("done", task.Done, done))
+			task.Done = done
+			task.CompletedAt = completionUpdate(done)
+			changed = true
+		}
+	}
+
+	s.tasks.tasks[task.ID] = task
+	if changed {
+		s.tasks.touchParents(ctx, task.ParentID)
+	}
+}
+
+type MemoryUserStore struct {
+	mu     sync.RWMutex
+	nextID uint
+	users  map[uint]models.User
+}
+
+func (s *MemoryUserStore) Create(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+
+	s.nextID++
+	user.ID = s.nextID
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *MemoryUserStore) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+type MemoryRefreshTokenStore struct {
+	mu     sync.Mutex
+	nextID uint
+	tokens map[uint]models.RefreshToken
+}
+
+func (s *MemoryRefreshTokenStore) Create(ctx context.Context, token *models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+
+	s.nextID++
+	token.ID = s.nextID
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	s.tokens[token.ID] = *token
+	return nil
+}
+
+func (s *MemoryRefreshTokenStore) GetByHash(ctx context.Context, hash string) (models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, ErrNotFound
+}
+
+func (s *MemoryRefreshTokenStore) MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[id]
+	if !ok || token.RevokedAt != nil {
+		return false, nil
+	}
+
+	token.RevokedAt = &at
+	s.tokens[id] = token
+	return true, nil
+}
+
+func (s *MemoryRefreshTokenStore) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			revokedAt := at
+			token.RevokedAt = &revokedAt
+			s.tokens[id] = token
+		}
+	}
+	return nil
+}
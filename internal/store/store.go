@@ -0,0 +1,484 @@
+// Package store defines the persistence interfaces used by the handlers,
+// with a gorm implementation for SQL databases and an in-memory one for
+// tests and dependency-free development.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/rank"
+)
+
+var (
+	ErrNotFound      = errors.New("record not found")
+	ErrDuplicate     = errors.New("duplicate record")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidOrder is returned when reordering with ids that are not
+	// exactly the ones being ordered.
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+// TaskStore persists tasks. Every lookup is scoped to the owning user, so a
+// task belonging to someone else is reported as ErrNotFound.
+//
+// Creating, updating and deleting subtasks bumps the versions of their
+// parents and keeps auto-completing parents in sync.
+//
+// Every change to a task is recorded in its history, attributed to the
+// Actor of the context it is made with.
+type TaskStore interface {
+	List(ctx context.Context, userID uint, opts TaskListOptions) (TaskList, error)
+	Get(ctx context.Context, userID, id uint) (models.Task, error)
+	// Subtree returns the task followed by all its descendants, by id.
+	Subtree(ctx context.Context, userID, id uint) ([]models.Task, error)
+	// Create appends the task to the user's manual order unless it has a
+	// position.
+	Create(ctx context.Context, task *models.Task) error
+	Update(ctx context.Context, task *models.Task) error
+	// Move places the task right before or after another of the user's
+	// tasks in their manual order, saving its new Position and Version.
+	// It returns ErrNotFound when there is no such anchor task.
+	Move(ctx context.Context, task *models.Task, anchorID uint, after bool) error
+	// CompleteOccurrence saves a recurring task that has been moved on to
+	// its next occurrence, recording the completed one.
+	CompleteOccurrence(ctx context.Context, task *models.Task, occurrence *models.TaskOccurrence) error
+	// Occurrences returns the completed occurrences of a task, most recent
+	// first.
+	Occurrences(ctx context.Context, userID, taskID uint) ([]models.TaskOccurrence, error)
+	// History returns the recorded changes to a task, oldest first.
+	History(ctx context.Context, userID, taskID uint) ([]models.TaskEvent, error)
+	// Delete moves the task to the trash together with its descendants, or
+	// moves its subtasks up to its own parent when deleteSubtasks is false.
+	Delete(ctx context.Context, task *models.Task, deleteSubtasks bool) error
+	// Trash returns the user's trashed tasks, most recently deleted first.
+	Trash(ctx context.Context, userID uint) ([]models.Task, error)
+	// GetTrashed is Get for a task in the trash.
+	GetTrashed(ctx context.Context, userID, id uint) (models.Task, error)
+	// Restore takes a trashed task out of the trash together with the
+	// subtasks deleted along with it. The task loses its parent if that is
+	// no longer a live task.
+	Restore(ctx context.Context, task *models.Task) error
+	// Purge permanently deletes a trashed task and the subtasks deleted
+	// along with it.
+	Purge(ctx context.Context, task *models.Task) error
+	// PurgeDeleted permanently deletes the tasks of every user that were
+	// trashed before the given time and returns how many there were.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ProjectStore persists projects, scoped to their owner like TaskStore.
+type ProjectStore interface {
+	// List returns the user's projects by position. Archived projects are
+	// only included when includeArchived is set.
+	List(ctx context.Context, userID uint, includeArchived bool) ([]models.Project, error)
+	Get(ctx context.Context, userID, id uint) (models.Project, error)
+	// Create appends the project after the user's others unless it has a
+	// position.
+	Create(ctx context.Context, project *models.Project) error
+	Update(ctx context.Context, project *models.Project) error
+	// Delete removes the project and moves its tasks to the trash, or to
+	// the Inbox when deleteTasks is false. Tasks of the project that are
+	// already in the trash move to the Inbox either way.
+	Delete(ctx context.Context, project *models.Project, deleteTasks bool) error
+}
+
+// TagStore persists tags, scoped to their owner like TaskStore. Tasks
+// attach tags by name through TaskStore, which creates missing ones.
+// Changes that alter the tags shown on tasks bump those tasks' versions.
+type TagStore interface {
+	// List returns the user's tags by name, with their task counts.
+	List(ctx context.Context, userID uint) ([]models.Tag, error)
+	Get(ctx context.Context, userID, id uint) (models.Tag, error)
+	// Create and Update return ErrDuplicate when the user already has a
+	// tag with the same name.
+	Create(ctx context.Context, tag *models.Tag) error
+	Update(ctx context.Context, tag *models.Tag) error
+	// Merge moves the tasks of source to target and deletes source.
+	Merge(ctx context.Context, source, target *models.Tag) error
+	Delete(ctx context.Context, tag *models.Tag) error
+}
+
+// CommentStore persists the comments on tasks. Lookups are scoped to a
+// task, so callers check that the user may see the task first. Adding and
+// removing comments bumps the task's version, as its comment count
+// changes.
+type CommentStore interface {
+	// List returns a page of the task's comments, oldest first. A zero
+	// limit returns all of them.
+	List(ctx context.Context, taskID uint, limit, offset int) (CommentList, error)
+	Get(ctx context.Context, taskID, id uint) (models.Comment, error)
+	Create(ctx context.Context, comment *models.Comment) error
+	// Update saves a new body for the comment.
+	Update(ctx context.Context, comment *models.Comment) error
+	Delete(ctx context.Context, comment *models.Comment) error
+}
+
+type CommentList struct {
+	Comments []models.Comment
+	Total    int64
+}
+
+// AttachmentStore persists the metadata of files attached to tasks. Like
+// comments, lookups are scoped to a task. The content lives in blob
+// storage: when an attachment or its task is deleted for good, its blob
+// key is queued as orphaned in the same transaction, and a sweeper deletes
+// the blobs later.
+type AttachmentStore interface {
+	// List returns the task's attachments, oldest first.
+	List(ctx context.Context, taskID uint) ([]models.Attachment, error)
+	Get(ctx context.Context, taskID, id uint) (models.Attachment, error)
+	Create(ctx context.Context, attachment *models.Attachment) error
+	Delete(ctx context.Context, attachment *models.Attachment) error
+	// OrphanedBlobs returns up to limit queued blob keys.
+	OrphanedBlobs(ctx context.Context, limit int) ([]string, error)
+	// ForgetBlobs removes keys whose blobs have been deleted from the queue.
+	ForgetBlobs(ctx context.Context, keys []string) error
+}
+
+// ChecklistStore persists the checklist items of tasks. Lookups are scoped
+// to a task. Every change bumps the task's version, as its checklist
+// progress is part of it, and syncs the Done flag of tasks with
+// ChecklistAutoComplete set: they are completed once all their items are
+// checked and reopened when one is added or unchecked.
+type ChecklistStore interface {
+	// List returns the task's checklist in position order.
+	List(ctx context.Context, taskID uint) ([]models.ChecklistItem, error)
+	Get(ctx context.Context, taskID, id uint) (models.ChecklistItem, error)
+	// Create appends the item to the task's checklist.
+	Create(ctx context.Context, item *models.ChecklistItem) error
+	// Update saves the item's text and checked state.
+	Update(ctx context.Context, item *models.ChecklistItem) error
+	Delete(ctx context.Context, item *models.ChecklistItem) error
+	// Reorder moves the task's items to the order of ids, which lists
+	// each of them once.
+	Reorder(ctx context.Context, taskID uint, ids []uint) error
+}
+
+type UserStore interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+}
+
+// RefreshTokenStore persists the hashes of issued refresh tokens.
+type RefreshTokenStore interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (models.RefreshToken, error)
+	// MarkUsed revokes a single token and reports whether it was still
+	// active. Only one caller can win for a given token.
+	MarkUsed(ctx context.Context, id uint, at time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+}
+
+// Stores groups the stores of one backend.
+type Stores struct {
+	Tasks         TaskStore
+	Projects      ProjectStore
+	Tags          TagStore
+	Comments      CommentStore
+	Attachments   AttachmentStore
+	Checklists    ChecklistStore
+	Users         UserStore
+	RefreshTokens RefreshTokenStore
+}
+
+// Actor is who makes the changes recorded in the task history: a user and
+// the ID of their request.
+type Actor struct {
+	UserID    uint
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a context attributing the task changes made with it to
+// actor. Changes made without an actor are attributed to the task's owner.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// newTaskEvent returns the history event for a change to one of userID's
+// tasks made with ctx.
+func newTaskEvent(ctx context.Context, userID, taskID uint, action string, changes models.TaskChanges) models.TaskEvent {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok {
+		actor.UserID = userID
+	}
+	if changes == nil {
+		changes = models.TaskChanges{}
+	}
+
+	return models.TaskEvent{
+		TaskID:    taskID,
+		UserID:    userID,
+		Action:    action,
+		Changes:   changes,
+		ActorID:   actor.UserID,
+		RequestID: actor.RequestID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// change returns the changes of an event that only changed one field.
+func change(field string, from, to interface{}) models.TaskChanges {
+	return models.TaskChanges{field: {From: from, To: to}}
+}
+
+// auditedFields returns the values of the task fields tracked by the
+// history, by JSON name. Derived and bookkeeping fields are left out.
+func auditedFields(task models.Task) map[string]interface{} {
+	return map[string]interface{}{
+		"title":                   task.Title,
+		"description":             task.Description,
+		"done":                    task.Done,
+		"start_at":                formatTime(task.StartAt),
+		"due_at":                  formatTime(task.DueAt),
+		"priority":                task.Priority,
+		"position":                task.Position,
+		"project_id":              task.ProjectID,
+		"parent_id":               task.ParentID,
+		"auto_complete":           task.AutoComplete,
+		"checklist_auto_complete": task.ChecklistAutoComplete,
+		"rrule":                   task.RRule,
+		"rrule_tz":                task.RRuleTZ,
+		"tags":                    uniqueTagNames(task.Tags),
+	}
+}
+
+// diffTask returns the tracked fields that differ between old and task.
+// For a new task, old is nil and the fields that are set are returned.
+func diffTask(old *models.Task, task models.Task) models.TaskChanges {
+	changes := models.TaskChanges{}
+	after := auditedFields(task)
+
+	if old == nil {
+		before := auditedFields(models.Task{})
+		for field, value := range after {
+			if !reflect.DeepEqual(value, before[field]) {
+				changes[field] = models.FieldChange{To: value}
+			}
+		}
+		return changes
+	}
+
+	before := auditedFields(*old)
+	for field, value := range after {
+		if !reflect.DeepEqual(value, before[field]) {
+			changes[field] = models.FieldChange{From: before[field], To: value}
+		}
+	}
+	return changes
+}
+
+// TaskFilter narrows a task listing. Nil fields are ignored.
+type TaskFilter struct {
+	// ProjectID limits the listing to one project, InboxOnly to tasks
+	// without a project.
+	ProjectID *uint
+	InboxOnly bool
+	// ParentID limits the listing to the subtasks of one task, RootOnly to
+	// tasks without a parent.
+	ParentID *uint
+	RootOnly bool
+	// Tags limits the listing to tasks with any of the named tags, or
+	// with all of them when AllTags is set.
+	Tags      []string
+	AllTags   bool
+	Done      *bool
+	Title     string
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	Overdue   *bool
+	// UpdatedSince limits the listing to tasks changed at or after the
+	// given time.
+	UpdatedSince *time.Time
+	// Now is the reference time for Overdue.
+	Now time.Time
+}
+
+// TaskListOptions controls filtering, ordering and pagination. Tasks are
+// ordered by Sort with NULLs last and the id as a tie breaker.
+type TaskListOptions struct {
+	Filter TaskFilter
+	Sort   string
+	Desc   bool
+	Limit  int
+	Offset int
+	After  *TaskCursor
+}
+
+// TaskCursor is the position of the last task of the previous page.
+type TaskCursor struct {
+	Value *string
+	ID    uint
+}
+
+type TaskList struct {
+	Tasks   []models.Task
+	Total   int64
+	HasMore bool
+}
+
+// positionNextTo returns the position of a task moved right before or
+// after the anchor position. neighbor is the position of the task on the
+// other side of the anchor, or nil when the anchor is at that end.
+func positionNextTo(anchor string, neighbor *string, after bool) string {
+	if after {
+		if neighbor == nil {
+			return rank.Between(anchor, "")
+		}
+		return rank.Between(anchor, *neighbor)
+	}
+
+	if neighbor == nil {
+		return rank.Between("", anchor)
+	}
+	return rank.Between(*neighbor, anchor)
+}
+
+// trackCompletion sets task.CompletedAt from the change of its Done flag:
+// to now when the task has just been completed, to the stored time while
+// it stays done, and to nil once it is reopened. old is nil for new tasks.
+func trackCompletion(task, old *models.Task) {
+	switch {
+	case !task.Done:
+		task.CompletedAt = nil
+	case old != nil && old.Done:
+		task.CompletedAt = old.CompletedAt
+	default:
+		now := time.Now().UTC()
+		task.CompletedAt = &now
+	}
+}
+
+// completionUpdate returns the completed_at value for a task whose Done
+// flag has just changed: now when done is true and the task has been
+// completed, nil when it is false and the task has been reopened.
+func completionUpdate(done bool) *time.Time {
+	if !done {
+		return nil
+	}
+	now := time.Now().UTC()
+	return &now
+}
+
+// samePermutation reports whether ids holds each of current exactly once.
+func samePermutation(current, ids []uint) bool {
+	if len(current) != len(ids) {
+		return false
+	}
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	current = slices.Clone(current)
+	slices.Sort(current)
+	return slices.Equal(sorted, current)
+}
+
+// uniqueTagNames returns names sorted and without duplicates.
+func uniqueTagNames(names []string) []string {
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if !slices.Contains(unique, name) {
+			unique = append(unique, name)
+		}
+	}
+	slices.Sort(unique)
+	return unique
+}
+
+type SortKind int
+
+const (
+	SortUint SortKind = iota
+	SortString
+	SortBool
+	SortTime
+)
+
+// TaskSortFields lists the columns tasks can be sorted by.
+var TaskSortFields = map[string]SortKind{
+	"id":           SortUint,
+	"title":        SortString,
+	"done":         SortBool,
+	"start_at":     SortTime,
+	"due_at":       SortTime,
+	"priority":     SortUint,
+	"position":     SortString,
+	"created_at":   SortTime,
+	"updated_at":   SortTime,
+	"completed_at": SortTime,
+}
+
+var TaskSortFieldNames = []string{
+	"id", "title", "done", "start_at", "due_at", "priority", "position",
+	"created_at", "updated_at", "completed_at",
+}
+
+// TaskSortValue returns the cursor representation of task's sort column,
+// or nil when the column is NULL.
+func TaskSortValue(task models.Task, sort string) *string {
+	var value string
+
+	switch sort {
+	case "title":
+		value = task.Title
+	case "done":
+		value = strconv.FormatBool(task.Done)
+	case "start_at":
+		return formatTime(task.StartAt)
+	case "due_at":
+		return formatTime(task.DueAt)
+	case "priority":
+		value = strconv.Itoa(task.Priority)
+	case "position":
+		value = task.Position
+	case "created_at":
+		return formatTime(&task.CreatedAt)
+	case "updated_at":
+		return formatTime(&task.UpdatedAt)
+	case "completed_at":
+		return formatTime(task.CompletedAt)
+	default:
+		value = strconv.FormatUint(uint64(task.ID), 10)
+	}
+
+	return &value
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	value := t.UTC().Format(time.RFC3339Nano)
+	return &value
+}
+
+// parseSortValue converts a cursor value back to the column's Go type.
+func parseSortValue(sort string, value string) (interface{}, error) {
+	var parsed interface{}
+	var err error
+
+	switch TaskSortFields[sort] {
+	case SortBool:
+		parsed, err = strconv.ParseBool(value)
+	case SortTime:
+		parsed, err = time.Parse(time.RFC3339Nano, value)
+	case SortUint:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 64)
+		parsed = uint(n)
+	default:
+		parsed = value
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,1184 @@
+package store_test
+
+import (
+	"context"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/testutils"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// forEachStore runs a test against every store implementation so they keep
+// the same behaviour.
+func forEachStore(t *testing.T, test func(t *testing.T, stores store.Stores)) {
+	t.Run("gorm", func(t *testing.T) {
+		test(t, store.NewGormStores(testutils.SetupTestDB(t), 0))
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, store.NewMemoryStores())
+	})
+}
+
+func createTasks(t *testing.T, tasks store.TaskStore, list ...models.Task) {
+	for i := range list {
+		if err := tasks.Create(context.Background(), &list[i]); err != nil {
+			t.Fatalf("Error creating task: %v", err)
+		}
+	}
+}
+
+func titles(tasks []models.Task) []string {
+	result := []string{}
+	for _, task := range tasks {
+		result = append(result, task.Title)
+	}
+	return result
+}
+
+func TestTaskStoreCRUD(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		task := models.Task{Title: "Write tests", UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &task))
+		assert.NotZero(t, task.ID)
+
+		got, err := stores.Tasks.Get(ctx, 1, task.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Write tests", got.Title)
+
+		_, err = stores.Tasks.Get(ctx, 2, task.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		got.Done = true
+		assert.NoError(t, stores.Tasks.Update(ctx, &got))
+
+		got, _ = stores.Tasks.Get(ctx, 1, task.ID)
+		assert.True(t, got.Done)
+
+		assert.NoError(t, stores.Tasks.Delete(ctx, &got, false))
+		_, err = stores.Tasks.Get(ctx, 1, task.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestTaskStoreListFilters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		now := time.Now().UTC()
+		past := now.Add(-time.Hour)
+		future := now.Add(time.Hour)
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "Buy milk", DueAt: &past, UserID: 1},
+			models.Task{Title: "Buy bread", DueAt: &future, UserID: 1},
+			models.Task{Title: "Pay 100% of rent", Done: true, DueAt: &past, UserID: 1},
+			models.Task{Title: "Buy milk", UserID: 2},
+		)
+
+		list := func(filter store.TaskFilter) []string {
+			filter.Now = now
+			result, err := stores.Tasks.List(context.Background(), 1, store.TaskListOptions{Filter: filter})
+			assert.NoError(t, err)
+			return titles(result.Tasks)
+		}
+
+		overdue, notOverdue, done := true, false, true
+
+		assert.Equal(t, []string{"Buy milk", "Buy bread", "Pay 100% of rent"}, list(store.TaskFilter{}))
+		assert.Equal(t, []string{"Buy milk", "Buy bread"}, list(store.TaskFilter{Title: "BUY"}))
+		assert.Equal(t, []string{"Pay 100% of rent"}, list(store.TaskFilter{Title: "0%"}))
+		assert.Equal(t, []string{"Buy milk"}, list(store.TaskFilter{Overdue: &overdue}))
+		assert.Equal(t, []string{"Buy bread", "Pay 100% of rent"}, list(store.TaskFilter{Overdue: &notOverdue}))
+		assert.Equal(t, []string{"Pay 100% of rent"}, list(store.TaskFilter{Done: &done}))
+		assert.Equal(t, []string{"Buy bread"}, list(store.TaskFilter{DueAfter: &now}))
+		assert.Equal(t, []string{"Buy milk", "Pay 100% of rent"}, list(store.TaskFilter{DueBefore: &now}))
+	})
+}
+
+func TestTaskStoreListSortAndCursor(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		later := day.Add(24 * time.Hour)
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "no due 1", UserID: 1},
+			models.Task{Title: "later", DueAt: &later, UserID: 1},
+			models.Task{Title: "day", DueAt: &day, UserID: 1},
+			models.Task{Title: "no due 2", UserID: 1},
+			models.Task{Title: "same day", DueAt: &day, UserID: 1},
+		)
+
+		for _, desc := range []bool{false, true} {
+			want := []string{"day", "same day", "later", "no due 1", "no due 2"}
+			if desc {
+				want = []string{"later", "same day", "day", "no due 2", "no due 1"}
+			}
+
+			var got []string
+			var after *store.TaskCursor
+			for {
+				page, err := stores.Tasks.List(context.Background(), 1, store.TaskListOptions{
+					Sort:  "due_at",
+					Desc:  desc,
+					Limit: 2,
+					After: after,
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, int64(5), page.Total)
+				got = append(got, titles(page.Tasks)...)
+
+				if !page.HasMore {
+					break
+				}
+				last := page.Tasks[len(page.Tasks)-1]
+				after = &store.TaskCursor{Value: store.TaskSortValue(last, "due_at"), ID: last.ID}
+			}
+
+			assert.Equal(t, want, got, "desc=%v", desc)
+		}
+	})
+}
+
+func TestTaskStoreListOffsetAndInvalidCursor(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "a", UserID: 1},
+			models.Task{Title: "b", UserID: 1},
+			models.Task{Title: "c", UserID: 1},
+		)
+
+		page, err := stores.Tasks.List(context.Background(), 1, store.TaskListOptions{Sort: "title", Limit: 1, Offset: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b"}, titles(page.Tasks))
+		assert.True(t, page.HasMore)
+
+		bad := "yesterday"
+		_, err = stores.Tasks.List(context.Background(), 1, store.TaskListOptions{
+			Sort:  "due_at",
+			After: &store.TaskCursor{Value: &bad, ID: 1},
+		})
+		assert.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+}
+
+func TestUserStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		user := models.User{Email: "user@example.com", PasswordHash: "hash"}
+		assert.NoError(t, stores.Users.Create(ctx, &user))
+		assert.NotZero(t, user.ID)
+
+		assert.Error(t, stores.Users.Create(ctx, &models.User{Email: "user@example.com"}))
+
+		got, err := stores.Users.GetByEmail(ctx, "user@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, got.ID)
+
+		_, err = stores.Users.GetByEmail(ctx, "missing@example.com")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestRefreshTokenStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+		expires := time.Now().Add(time.Hour)
+
+		first := models.RefreshToken{UserID: 1, FamilyID: "family", TokenHash: "first", ExpiresAt: expires}
+		second := models.RefreshToken{UserID: 1, FamilyID: "family", TokenHash: "second", ExpiresAt: expires}
+		assert.NoError(t, stores.RefreshTokens.Create(ctx, &first))
+		assert.NoError(t, stores.RefreshTokens.Create(ctx, &second))
+
+		got, err := stores.RefreshTokens.GetByHash(ctx, "first")
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, got.ID)
+
+		_, err = stores.RefreshTokens.GetByHash(ctx, "missing")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		used, err := stores.RefreshTokens.MarkUsed(ctx, first.ID, time.Now())
+		assert.NoError(t, err)
+		assert.True(t, used)
+
+		used, err = stores.RefreshTokens.MarkUsed(ctx, first.ID, time.Now())
+		assert.NoError(t, err)
+		assert.False(t, used)
+
+		assert.NoError(t, stores.RefreshTokens.RevokeFamily(ctx, "family", time.Now()))
+
+		got, _ = stores.RefreshTokens.GetByHash(ctx, "second")
+		assert.NotNil(t, got.RevokedAt)
+	})
+}
+
+func TestGormStoreQueryTimeout(t *testing.T) {
+	stores := store.NewGormStores(testutils.SetupTestDB(t), time.Nanosecond)
+
+	_, err := stores.Tasks.List(context.Background(), 1, store.TaskListOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestProjectStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		work := models.Project{Name: "Work", UserID: 1}
+		home := models.Project{Name: "Home", UserID: 1}
+		old := models.Project{Name: "Old", Archived: true, UserID: 1}
+		other := models.Project{Name: "Other", UserID: 2}
+		for _, project := range []*models.Project{&work, &home, &old, &other} {
+			assert.NoError(t, stores.Projects.Create(ctx, project))
+		}
+		assert.Equal(t, 1, work.Position)
+		assert.Equal(t, 2, home.Position)
+		assert.Equal(t, 1, other.Position)
+
+		home.Position = 0
+		assert.NoError(t, stores.Projects.Update(ctx, &home))
+
+		projects, err := stores.Projects.List(ctx, 1, false)
+		assert.NoError(t, err)
+		assert.Len(t, projects, 2)
+		assert.Equal(t, "Home", projects[0].Name)
+		assert.Equal(t, "Work", projects[1].Name)
+
+		projects, err = stores.Projects.List(ctx, 1, true)
+		assert.NoError(t, err)
+		assert.Len(t, projects, 3)
+
+		_, err = stores.Projects.Get(ctx, 2, work.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestProjectStoreDelete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		keep := models.Project{Name: "Keep tasks", UserID: 1}
+		drop := models.Project{Name: "Drop tasks", UserID: 1}
+		assert.NoError(t, stores.Projects.Create(ctx, &keep))
+		assert.NoError(t, stores.Projects.Create(ctx, &drop))
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "inbox", UserID: 1, Version: 1},
+			models.Task{Title: "kept", ProjectID: &keep.ID, UserID: 1, Version: 1},
+			models.Task{Title: "dropped", ProjectID: &drop.ID, UserID: 1, Version: 1},
+		)
+
+		list := func(filter store.TaskFilter) []string {
+			result, err := stores.Tasks.List(ctx, 1, store.TaskListOptions{Filter: filter})
+			assert.NoError(t, err)
+			return titles(result.Tasks)
+		}
+
+		assert.Equal(t, []string{"inbox"}, list(store.TaskFilter{InboxOnly: true}))
+		assert.Equal(t, []string{"kept"}, list(store.TaskFilter{ProjectID: &keep.ID}))
+
+		assert.NoError(t, stores.Projects.Delete(ctx, &keep, false))
+		assert.NoError(t, stores.Projects.Delete(ctx, &drop, true))
+
+		assert.Equal(t, []string{"inbox", "kept"}, list(store.TaskFilter{InboxOnly: true}))
+		assert.Equal(t, []string{"inbox", "kept"}, list(store.TaskFilter{}))
+
+		result, _ := stores.Tasks.List(ctx, 1, store.TaskListOptions{Sort: "title"})
+		assert.Equal(t, uint(2), result.Tasks[1].Version)
+
+		_, err := stores.Projects.Get(ctx, 1, keep.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}
+
+func TestProjectStoreDeleteTouchesParents(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		project := models.Project{Name: "Errands", UserID: 1}
+		assert.NoError(t, stores.Projects.Create(ctx, &project))
+
+		parent := models.Task{Title: "parent", AutoComplete: true, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &parent))
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "done", ParentID: &parent.ID, Done: true, UserID: 1, Version: 1},
+			models.Task{Title: "open", ParentID: &parent.ID, ProjectID: &project.ID, UserID: 1, Version: 1},
+		)
+
+		before, _ := stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.False(t, before.Done)
+
+		// The parent is outside the project but loses its open subtask, so
+		// it is bumped and completed like on a single delete.
+		assert.NoError(t, stores.Projects.Delete(ctx, &project, true))
+
+		after, err := stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.NoError(t, err)
+		assert.True(t, after.Done)
+		assert.Greater(t, after.Version, before.Version)
+		assert.Equal(t, models.SubtaskProgress{Done: 1, Total: 1}, after.Subtasks)
+	})
+}
+
+func TestTaskStoreTags(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "both", Tags: []string{"urgent", "backend", "urgent"}, UserID: 1, Version: 1},
+			models.Task{Title: "urgent", Tags: []string{"urgent"}, UserID: 1, Version: 1},
+			models.Task{Title: "none", UserID: 1, Version: 1},
+			models.Task{Title: "other user", Tags: []string{"urgent"}, UserID: 2, Version: 1},
+		)
+
+		got, err := stores.Tasks.Get(ctx, 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"backend", "urgent"}, got.Tags)
+
+		list := func(filter store.TaskFilter) []string {
+			result, err := stores.Tasks.List(ctx, 1, store.TaskListOptions{Filter: filter})
+			assert.NoError(t, err)
+			return titles(result.Tasks)
+		}
+
+		assert.Equal(t, []string{"both", "urgent"}, list(store.TaskFilter{Tags: []string{"urgent", "backend"}}))
+		assert.Equal(t, []string{"both"}, list(store.TaskFilter{Tags: []string{"urgent", "backend"}, AllTags: true}))
+		assert.Equal(t, []string{"both"}, list(store.TaskFilter{Tags: []string{"backend", "backend"}, AllTags: true}))
+		assert.Empty(t, list(store.TaskFilter{Tags: []string{"missing"}}))
+
+		got.Tags = []string{"waiting-on-client"}
+		assert.NoError(t, stores.Tasks.Update(ctx, &got))
+
+		tags, err := stores.Tags.List(ctx, 1)
+		assert.NoError(t, err)
+		counts := map[string]int64{}
+		for _, tag := range tags {
+			counts[tag.Name] = tag.TaskCount
+		}
+		assert.Equal(t, map[string]int64{"backend": 0, "urgent": 1, "waiting-on-client": 1}, counts)
+	})
+}
+
+func TestTagStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "a", Tags: []string{"bug", "defect"}, UserID: 1, Version: 1},
+			models.Task{Title: "b", Tags: []string{"defect"}, UserID: 1, Version: 1},
+			models.Task{Title: "c", Tags: []string{"later"}, UserID: 1, Version: 1},
+		)
+
+		tags, err := stores.Tags.List(ctx, 1)
+		assert.NoError(t, err)
+		assert.Len(t, tags, 3)
+		bug, defect, later := tags[0], tags[1], tags[2]
+
+		assert.ErrorIs(t, stores.Tags.Create(ctx, &models.Tag{Name: "bug", UserID: 1}), store.ErrDuplicate)
+		assert.NoError(t, stores.Tags.Create(ctx, &models.Tag{Name: "bug", UserID: 2}))
+
+		_, err = stores.Tags.Get(ctx, 2, bug.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		later.Name = "bug"
+		assert.ErrorIs(t, stores.Tags.Update(ctx, &later), store.ErrDuplicate)
+		later.Name = "someday"
+		assert.NoError(t, stores.Tags.Update(ctx, &later))
+
+		task, _ := stores.Tasks.Get(ctx, 1, 3)
+		assert.Equal(t, []string{"someday"}, task.Tags)
+		assert.Equal(t, uint(2), task.Version)
+
+		assert.NoError(t, stores.Tags.Merge(ctx, &defect, &bug))
+		bug, err = stores.Tags.Get(ctx, 1, bug.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), bug.TaskCount)
+		_, err = stores.Tags.Get(ctx, 1, defect.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		task, _ = stores.Tasks.Get(ctx, 1, 1)
+		assert.Equal(t, []string{"bug"}, task.Tags)
+
+		assert.NoError(t, stores.Tags.Delete(ctx, &bug))
+		task, _ = stores.Tasks.Get(ctx, 1, 2)
+		assert.Empty(t, task.Tags)
+		assert.Equal(t, uint(3), task.Version)
+
+		tags, _ = stores.Tags.List(ctx, 1)
+		assert.Len(t, tags, 1)
+	})
+}
+
+func TestTagStoreHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := store.WithActor(context.Background(), store.Actor{UserID: 1, RequestID: "req-1"})
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "a", Tags: []string{"bug", "defect"}, UserID: 1, Version: 1},
+			models.Task{Title: "b", Tags: []string{"defect"}, UserID: 1, Version: 1},
+		)
+		tags, _ := stores.Tags.List(ctx, 1)
+		bug, defect := tags[0], tags[1]
+
+		defect.Name = "flaw"
+		assert.NoError(t, stores.Tags.Update(ctx, &defect))
+		assert.NoError(t, stores.Tags.Merge(ctx, &defect, &bug))
+		assert.NoError(t, stores.Tags.Delete(ctx, &bug))
+
+		for _, id := range []uint{1, 2} {
+			events, err := stores.Tasks.History(ctx, 1, id)
+			assert.NoError(t, err)
+			if assert.Len(t, events, 4) {
+				for _, event := range events[1:] {
+					assert.Equal(t, models.TaskUpdated, event.Action)
+					assert.Equal(t, []string{"tags"}, changedFields(event))
+					assert.Equal(t, "req-1", event.RequestID)
+				}
+			}
+		}
+
+		events, _ := stores.Tasks.History(ctx, 1, 2)
+		if assert.Len(t, events, 4) {
+			assert.Equal(t, []string{"defect"}, stringValues(events[1].Changes["tags"].From))
+			assert.Equal(t, []string{"flaw"}, stringValues(events[1].Changes["tags"].To))
+			assert.Equal(t, []string{"bug"}, stringValues(events[2].Changes["tags"].To))
+			assert.Empty(t, stringValues(events[3].Changes["tags"].To))
+		}
+	})
+}
+
+// stringValues converts a recorded string list, which the gorm store
+// returns as decoded JSON, to a []string.
+func stringValues(value interface{}) []string {
+	values := []string{}
+	switch v := value.(type) {
+	case []string:
+		values = append(values, v...)
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, item.(string))
+		}
+	}
+	return values
+}
+
+func TestTaskStoreAutoCompleteRecurring(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		due := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+		parent := models.Task{Title: "parent", AutoComplete: true, UserID: 1, Version: 1,
+			RRule: "FREQ=WEEKLY", RRuleStart: &due, DueAt: &due}
+		assert.NoError(t, stores.Tasks.Create(ctx, &parent))
+		child := models.Task{Title: "child", ParentID: &parent.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &child))
+
+		// Completing every subtask would complete the parent without moving
+		// it to its next occurrence, so it is left open.
+		child.Done = true
+		assert.NoError(t, stores.Tasks.Update(ctx, &child))
+		got, _ := stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.False(t, got.Done)
+		assert.Nil(t, got.CompletedAt)
+		assert.Equal(t, due, got.DueAt.UTC())
+		assert.Equal(t, models.SubtaskProgress{Done: 1, Total: 1}, got.Subtasks)
+	})
+}
+
+func TestTaskStoreSubtasks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		root := models.Task{Title: "root", AutoComplete: true, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &root))
+		child := models.Task{Title: "child", ParentID: &root.ID, AutoComplete: true, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &child))
+		sibling := models.Task{Title: "sibling", ParentID: &root.ID, Done: true, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &sibling))
+		leaf := models.Task{Title: "leaf", ParentID: &child.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &leaf))
+
+		got, _ := stores.Tasks.Get(ctx, 1, root.ID)
+		assert.Equal(t, models.SubtaskProgress{Done: 1, Total: 2}, got.Subtasks)
+		assert.False(t, got.Done)
+		assert.Equal(t, uint(3), got.Version)
+
+		subtree, err := stores.Tasks.Subtree(ctx, 1, root.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"root", "child", "sibling", "leaf"}, titles(subtree))
+
+		_, err = stores.Tasks.Subtree(ctx, 2, root.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		// Completing the leaf completes child, which completes root.
+		leaf.Done = true
+		assert.NoError(t, stores.Tasks.Update(ctx, &leaf))
+		got, _ = stores.Tasks.Get(ctx, 1, root.ID)
+		assert.True(t, got.Done)
+		assert.Equal(t, models.SubtaskProgress{Done: 2, Total: 2}, got.Subtasks)
+
+		leaf.Done = false
+		assert.NoError(t, stores.Tasks.Update(ctx, &leaf))
+		got, _ = stores.Tasks.Get(ctx, 1, root.ID)
+		assert.False(t, got.Done)
+
+		list, _ := stores.Tasks.List(ctx, 1, store.TaskListOptions{Filter: store.TaskFilter{RootOnly: true}})
+		assert.Equal(t, []string{"root"}, titles(list.Tasks))
+		list, _ = stores.Tasks.List(ctx, 1, store.TaskListOptions{Filter: store.TaskFilter{ParentID: &root.ID}})
+		assert.Equal(t, []string{"child", "sibling"}, titles(list.Tasks))
+
+		// Deleting child moves leaf up to root.
+		child, _ = stores.Tasks.Get(ctx, 1, child.ID)
+		assert.NoError(t, stores.Tasks.Delete(ctx, &child, false))
+		leaf, _ = stores.Tasks.Get(ctx, 1, leaf.ID)
+		assert.Equal(t, root.ID, *leaf.ParentID)
+
+		assert.NoError(t, stores.Tasks.Delete(ctx, &root, true))
+		list, _ = stores.Tasks.List(ctx, 1, store.TaskListOptions{})
+		assert.Empty(t, list.Tasks)
+	})
+}
+
+func TestTaskStoreOccurrences(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+		first := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+		second := first.AddDate(0, 0, 7)
+
+		task := models.Task{Title: "Weekly report", RRule: "FREQ=WEEKLY", RRuleStart: &first, DueAt: &first, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &task))
+
+		for i, due := range []time.Time{first, second} {
+			dueAt := due
+			next := due.AddDate(0, 0, 7)
+			task.DueAt = &next
+			task.Version++
+			occurrence := models.TaskOccurrence{DueAt: &dueAt, CompletedAt: due.Add(time.Hour)}
+			assert.NoError(t, stores.Tasks.CompleteOccurrence(ctx, &task, &occurrence))
+			assert.Equal(t, task.ID, occurrence.TaskID, i)
+		}
+
+		got, _ := stores.Tasks.Get(ctx, 1, task.ID)
+		assert.Equal(t, first.AddDate(0, 0, 14), got.DueAt.UTC())
+		assert.Equal(t, first, got.RRuleStart.UTC())
+
+		occurrences, err := stores.Tasks.Occurrences(ctx, 1, task.ID)
+		assert.NoError(t, err)
+		assert.Len(t, occurrences, 2)
+		assert.Equal(t, second, occurrences[0].DueAt.UTC())
+
+		occurrences, err = stores.Tasks.Occurrences(ctx, 2, task.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, occurrences)
+
+		assert.NoError(t, stores.Tasks.Delete(ctx, &got, false))
+		occurrences, _ = stores.Tasks.Occurrences(ctx, 1, task.ID)
+		assert.Empty(t, occurrences)
+	})
+}
+
+func TestTaskStorePositionsWithTrash(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "a", UserID: 1, Version: 1},
+			models.Task{Title: "b", UserID: 1, Version: 1},
+			models.Task{Title: "c", UserID: 1, Version: 1},
+		)
+
+		// Tasks created or moved while others are in the trash must not
+		// take their positions, or the two collide on restore.
+		last, _ := stores.Tasks.Get(ctx, 1, 3)
+		assert.NoError(t, stores.Tasks.Delete(ctx, &last, false))
+		createTasks(t, stores.Tasks, models.Task{Title: "d", UserID: 1, Version: 1})
+
+		middle, _ := stores.Tasks.Get(ctx, 1, 2)
+		assert.NoError(t, stores.Tasks.Delete(ctx, &middle, false))
+		moved, _ := stores.Tasks.Get(ctx, 1, 4)
+		assert.NoError(t, stores.Tasks.Move(ctx, &moved, 1, true))
+
+		for _, id := range []uint{2, 3} {
+			task, err := stores.Tasks.GetTrashed(ctx, 1, id)
+			assert.NoError(t, err)
+			assert.NoError(t, stores.Tasks.Restore(ctx, &task))
+		}
+
+		list, err := stores.Tasks.List(ctx, 1, store.TaskListOptions{Sort: "position"})
+		assert.NoError(t, err)
+		positions := map[string]bool{}
+		for _, task := range list.Tasks {
+			positions[task.Position] = true
+		}
+		assert.Len(t, positions, 4)
+		assert.Equal(t, []string{"a", "d", "b", "c"}, titles(list.Tasks))
+	})
+}
+
+func TestTaskStoreMove(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		createTasks(t, stores.Tasks,
+			models.Task{Title: "a", Priority: 4, UserID: 1, Version: 1},
+			models.Task{Title: "b", Priority: 1, UserID: 1, Version: 1},
+			models.Task{Title: "c", Priority: 2, UserID: 1, Version: 1},
+			models.Task{Title: "d", Priority: 4, UserID: 1, Version: 1},
+		)
+
+		order := func(sort string) []string {
+			list, err := stores.Tasks.List(ctx, 1, store.TaskListOptions{Sort: sort})
+			assert.NoError(t, err)
+			return titles(list.Tasks)
+		}
+
+		assert.Equal(t, []string{"a", "b", "c", "d"}, order("position"))
+		assert.Equal(t, []string{"b", "c", "a", "d"}, order("priority"))
+
+		move := func(id, anchor uint, after bool) {
+			task, err := stores.Tasks.Get(ctx, 1, id)
+			assert.NoError(t, err)
+			task.Version++
+			assert.NoError(t, stores.Tasks.Move(ctx, &task, anchor, after))
+		}
+
+		move(4, 1, false)
+		assert.Equal(t, []string{"d", "a", "b", "c"}, order("position"))
+		move(1, 3, true)
+		assert.Equal(t, []string{"d", "b", "c", "a"}, order("position"))
+		move(3, 2, false)
+		assert.Equal(t, []string{"d", "c", "b", "a"}, order("position"))
+		move(1, 4, true)
+		assert.Equal(t, []string{"d", "a", "c", "b"}, order("position"))
+
+		task, _ := stores.Tasks.Get(ctx, 1, 1)
+		assert.Equal(t, uint(3), task.Version)
+
+		createTasks(t, stores.Tasks, models.Task{Title: "e", UserID: 1, Version: 1})
+		assert.Equal(t, []string{"d", "a", "c", "b", "e"}, order("position"))
+
+		assert.ErrorIs(t, stores.Tasks.Move(ctx, &task, 99, true), store.ErrNotFound)
+	})
+}
+
+func TestTaskStoreTrash(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		parent := models.Task{Title: "parent", Tags: []string{"work"}, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &parent))
+		child := models.Task{Title: "child", ParentID: &parent.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &child))
+		createTasks(t, stores.Tasks, models.Task{Title: "other", UserID: 1, Version: 1})
+
+		tagCount := func() int64 {
+			tags, err := stores.Tags.List(ctx, 1)
+			assert.NoError(t, err)
+			return tags[0].TaskCount
+		}
+		list := func() []string {
+			result, err := stores.Tasks.List(ctx, 1, store.TaskListOptions{Sort: "title"})
+			assert.NoError(t, err)
+			return titles(result.Tasks)
+		}
+
+		parent, _ = stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.NoError(t, stores.Tasks.Delete(ctx, &parent, true))
+		assert.Equal(t, []string{"other"}, list())
+		assert.Zero(t, tagCount())
+
+		trash, err := stores.Tasks.Trash(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"child", "parent"}, titles(trash))
+		assert.True(t, trash[0].DeletedAt.Valid)
+
+		trash, _ = stores.Tasks.Trash(ctx, 2)
+		assert.Empty(t, trash)
+		_, err = stores.Tasks.GetTrashed(ctx, 2, parent.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		// Restoring the parent brings back the child deleted with it.
+		trashed, err := stores.Tasks.GetTrashed(ctx, 1, parent.ID)
+		assert.NoError(t, err)
+		assert.NoError(t, stores.Tasks.Restore(ctx, &trashed))
+		assert.Equal(t, []string{"child", "other", "parent"}, list())
+		assert.Equal(t, int64(1), tagCount())
+
+		child, _ = stores.Tasks.Get(ctx, 1, child.ID)
+		assert.Equal(t, parent.ID, *child.ParentID)
+		assert.False(t, child.DeletedAt.Valid)
+		_, err = stores.Tasks.GetTrashed(ctx, 1, parent.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		// A task restored without its parent moves to the top level.
+		assert.NoError(t, stores.Tasks.Delete(ctx, &child, false))
+		parent, _ = stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.NoError(t, stores.Tasks.Delete(ctx, &parent, false))
+
+		child, _ = stores.Tasks.GetTrashed(ctx, 1, child.ID)
+		assert.NoError(t, stores.Tasks.Restore(ctx, &child))
+		child, _ = stores.Tasks.Get(ctx, 1, child.ID)
+		assert.Nil(t, child.ParentID)
+
+		assert.NoError(t, stores.Tasks.Purge(ctx, &parent))
+		trash, _ = stores.Tasks.Trash(ctx, 1)
+		assert.Empty(t, trash)
+		assert.ErrorIs(t, stores.Tasks.Purge(ctx, &parent), store.ErrNotFound)
+
+		purged, err := stores.Tasks.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+
+		other, _ := stores.Tasks.Get(ctx, 1, child.ID+1)
+		assert.NoError(t, stores.Tasks.Delete(ctx, &other, false))
+
+		purged, _ = stores.Tasks.PurgeDeleted(ctx, time.Now().Add(-time.Minute))
+		assert.Zero(t, purged)
+		purged, _ = stores.Tasks.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+		assert.Equal(t, int64(1), purged)
+		assert.Equal(t, []string{"child"}, list())
+	})
+}
+
+func TestTaskStoreTimestamps(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		parent := models.Task{Title: "parent", AutoComplete: true, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &parent))
+		child := models.Task{Title: "child", ParentID: &parent.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &child))
+
+		got, _ := stores.Tasks.Get(ctx, 1, child.ID)
+		assert.False(t, got.CreatedAt.IsZero())
+		assert.False(t, got.UpdatedAt.Before(got.CreatedAt))
+		assert.Nil(t, got.CompletedAt)
+		created := got.CreatedAt
+
+		since := time.Now()
+		time.Sleep(5 * time.Millisecond)
+
+		got.Done = true
+		got.CreatedAt = time.Time{}
+		assert.NoError(t, stores.Tasks.Update(ctx, &got))
+		got, _ = stores.Tasks.Get(ctx, 1, child.ID)
+		assert.WithinDuration(t, created, got.CreatedAt, time.Millisecond)
+		assert.True(t, got.UpdatedAt.After(since))
+		if assert.NotNil(t, got.CompletedAt) {
+			assert.True(t, got.CompletedAt.After(since))
+		}
+		completed := *got.CompletedAt
+
+		// Completing the child completed its auto-completing parent.
+		parent, _ = stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.NotNil(t, parent.CompletedAt)
+
+		got.Title = "renamed child"
+		assert.NoError(t, stores.Tasks.Update(ctx, &got))
+		got, _ = stores.Tasks.Get(ctx, 1, child.ID)
+		assert.WithinDuration(t, completed, *got.CompletedAt, time.Millisecond)
+
+		list, err := stores.Tasks.List(ctx, 1, store.TaskListOptions{Filter: store.TaskFilter{UpdatedSince: &since}})
+		assert.NoError(t, err)
+		assert.Len(t, list.Tasks, 2)
+
+		later := time.Now()
+		time.Sleep(5 * time.Millisecond)
+
+		got.Done = false
+		assert.NoError(t, stores.Tasks.Update(ctx, &got))
+		got, _ = stores.Tasks.Get(ctx, 1, child.ID)
+		assert.Nil(t, got.CompletedAt)
+
+		list, _ = stores.Tasks.List(ctx, 1, store.TaskListOptions{Filter: store.TaskFilter{UpdatedSince: &later}, Sort: "title"})
+		assert.Equal(t, []string{"parent", "renamed child"}, titles(list.Tasks))
+		parent, _ = stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.Nil(t, parent.CompletedAt)
+	})
+}
+
+func actions(events []models.TaskEvent) []string {
+	result := []string{}
+	for _, event := range events {
+		result = append(result, event.Action)
+	}
+	return result
+}
+
+func changedFields(event models.TaskEvent) []string {
+	fields := []string{}
+	for field := range event.Changes {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+func TestTaskStoreHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := store.WithActor(context.Background(), store.Actor{UserID: 1, RequestID: "req-1"})
+
+		parent := models.Task{Title: "parent", AutoComplete: true, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &parent))
+		child := models.Task{Title: "child", ParentID: &parent.ID, Tags: []string{"home"}, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &child))
+		other := models.Task{Title: "other", UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &other))
+
+		events, err := stores.Tasks.History(ctx, 1, child.ID)
+		assert.NoError(t, err)
+		if assert.Equal(t, []string{models.TaskCreated}, actions(events)) {
+			assert.Equal(t, uint(1), events[0].ActorID)
+			assert.Equal(t, "req-1", events[0].RequestID)
+			assert.Equal(t, []string{"parent_id", "position", "tags", "title"}, changedFields(events[0]))
+			assert.Nil(t, events[0].Changes["title"].From)
+			assert.Equal(t, "child", events[0].Changes["title"].To)
+		}
+
+		ctx = store.WithActor(context.Background(), store.Actor{UserID: 1, RequestID: "req-2"})
+		child, _ = stores.Tasks.Get(ctx, 1, child.ID)
+		child.Title, child.Done = "renamed", true
+		assert.NoError(t, stores.Tasks.Update(ctx, &child))
+		// Saving an unchanged task records nothing.
+		assert.NoError(t, stores.Tasks.Update(ctx, &child))
+
+		events, _ = stores.Tasks.History(ctx, 1, child.ID)
+		if assert.Equal(t, []string{models.TaskCreated, models.TaskUpdated}, actions(events)) {
+			assert.Equal(t, "req-2", events[1].RequestID)
+			assert.Equal(t, []string{"done", "title"}, changedFields(events[1]))
+			assert.Equal(t, "child", events[1].Changes["title"].From)
+			assert.Equal(t, "renamed", events[1].Changes["title"].To)
+		}
+
+		// Completing the child completed its parent in the same request.
+		events, _ = stores.Tasks.History(ctx, 1, parent.ID)
+		if assert.Equal(t, []string{models.TaskCreated, models.TaskUpdated}, actions(events)) {
+			assert.Equal(t, "req-2", events[1].RequestID)
+			assert.Equal(t, models.FieldChange{From: false, To: true}, events[1].Changes["done"])
+		}
+
+		assert.NoError(t, stores.Tasks.Move(ctx, &other, parent.ID, false))
+		events, _ = stores.Tasks.History(ctx, 1, other.ID)
+		if assert.Equal(t, []string{models.TaskCreated, models.TaskUpdated}, actions(events)) {
+			assert.Equal(t, []string{"position"}, changedFields(events[1]))
+		}
+
+		// Changes made without an actor are attributed to the owner.
+		assert.NoError(t, stores.Tasks.Delete(context.Background(), &parent, true))
+		for _, id := range []uint{parent.ID, child.ID} {
+			events, _ = stores.Tasks.History(ctx, 1, id)
+			if assert.Equal(t, models.TaskDeleted, events[len(events)-1].Action) {
+				assert.Equal(t, uint(1), events[len(events)-1].ActorID)
+				assert.Empty(t, events[len(events)-1].RequestID)
+			}
+		}
+
+		parent, _ = stores.Tasks.GetTrashed(ctx, 1, parent.ID)
+		assert.NoError(t, stores.Tasks.Restore(ctx, &parent))
+		events, _ = stores.Tasks.History(ctx, 1, child.ID)
+		assert.Equal(t, []string{models.TaskCreated, models.TaskUpdated, models.TaskDeleted, models.TaskRestored}, actions(events))
+
+		events, _ = stores.Tasks.History(ctx, 2, child.ID)
+		assert.Empty(t, events)
+
+		// The history outlives the task.
+		assert.NoError(t, stores.Tasks.Delete(ctx, &other, true))
+		assert.NoError(t, stores.Tasks.Purge(ctx, &other))
+		events, _ = stores.Tasks.History(ctx, 1, other.ID)
+		if assert.NotEmpty(t, events) {
+			assert.Equal(t, models.TaskDeleted, events[len(events)-2].Action)
+			assert.Equal(t, models.TaskPurged, events[len(events)-1].Action)
+		}
+	})
+}
+
+func TestTaskStorePurgeDeletedHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		parent := models.Task{Title: "parent", UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &parent))
+		child := models.Task{Title: "child", ParentID: &parent.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &child))
+		assert.NoError(t, stores.Tasks.Delete(ctx, &parent, true))
+
+		purged, err := stores.Tasks.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), purged)
+
+		for _, id := range []uint{parent.ID, child.ID} {
+			events, err := stores.Tasks.History(ctx, 1, id)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{models.TaskCreated, models.TaskDeleted, models.TaskPurged}, actions(events))
+			if len(events) == 3 {
+				assert.Equal(t, uint(1), events[2].ActorID)
+			}
+		}
+	})
+}
+
+func TestProjectStoreDeleteHistory(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		work := models.Project{Name: "Work", UserID: 1}
+		home := models.Project{Name: "Home", UserID: 1}
+		assert.NoError(t, stores.Projects.Create(ctx, &work))
+		assert.NoError(t, stores.Projects.Create(ctx, &home))
+
+		report := models.Task{Title: "report", ProjectID: &work.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &report))
+		chart := models.Task{Title: "chart", ParentID: &report.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &chart))
+		plants := models.Task{Title: "plants", ProjectID: &home.ID, UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &plants))
+
+		assert.NoError(t, stores.Projects.Delete(ctx, &work, true))
+		assert.NoError(t, stores.Projects.Delete(ctx, &home, false))
+
+		events, _ := stores.Tasks.History(ctx, 1, report.ID)
+		assert.Equal(t, []string{models.TaskCreated, models.TaskDeleted}, actions(events))
+
+		events, _ = stores.Tasks.History(ctx, 1, chart.ID)
+		if assert.Equal(t, []string{models.TaskCreated, models.TaskUpdated}, actions(events)) {
+			assert.Equal(t, []string{"parent_id"}, changedFields(events[1]))
+			assert.Nil(t, events[1].Changes["parent_id"].To)
+		}
+
+		events, _ = stores.Tasks.History(ctx, 1, plants.ID)
+		if assert.Equal(t, []string{models.TaskCreated, models.TaskUpdated}, actions(events)) {
+			assert.Equal(t, []string{"project_id"}, changedFields(events[1]))
+		}
+	})
+}
+
+func TestCommentStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		task := models.Task{Title: "task", UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &task))
+
+		for _, body := range []string{"first", "second", "third"} {
+			comment := models.Comment{TaskID: task.ID, AuthorID: 1, Body: body}
+			assert.NoError(t, stores.Comments.Create(ctx, &comment))
+			assert.False(t, comment.CreatedAt.IsZero())
+		}
+
+		got, _ := stores.Tasks.Get(ctx, 1, task.ID)
+		assert.Equal(t, int64(3), got.CommentCount)
+		assert.Equal(t, uint(4), got.Version)
+
+		list, err := stores.Comments.List(ctx, task.ID, 2, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), list.Total)
+		if assert.Len(t, list.Comments, 2) {
+			assert.Equal(t, "second", list.Comments[0].Body)
+			assert.Equal(t, "third", list.Comments[1].Body)
+		}
+
+		comment, err := stores.Comments.Get(ctx, task.ID, list.Comments[0].ID)
+		assert.NoError(t, err)
+		_, err = stores.Comments.Get(ctx, task.ID+1, comment.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		comment.Body = "edited"
+		assert.NoError(t, stores.Comments.Update(ctx, &comment))
+		comment, _ = stores.Comments.Get(ctx, task.ID, comment.ID)
+		assert.Equal(t, "edited", comment.Body)
+		assert.False(t, comment.UpdatedAt.Before(comment.CreatedAt))
+
+		assert.NoError(t, stores.Comments.Delete(ctx, &comment))
+		assert.ErrorIs(t, stores.Comments.Delete(ctx, &comment), store.ErrNotFound)
+
+		page, _ := stores.Tasks.List(ctx, 1, store.TaskListOptions{})
+		if assert.Len(t, page.Tasks, 1) {
+			assert.Equal(t, int64(2), page.Tasks[0].CommentCount)
+		}
+
+		// Comments are purged with their task.
+		assert.NoError(t, stores.Tasks.Delete(ctx, &task, true))
+		assert.NoError(t, stores.Tasks.Purge(ctx, &task))
+		list, _ = stores.Comments.List(ctx, task.ID, 0, 0)
+		assert.Empty(t, list.Comments)
+	})
+}
+
+func TestAttachmentStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		task := models.Task{Title: "task", UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &task))
+
+		var attachments []models.Attachment
+		for _, name := range []string{"a.png", "b.pdf", "c.txt"} {
+			attachment := models.Attachment{TaskID: task.ID, UploaderID: 1, Filename: name,
+				ContentType: "text/plain", Size: 1, Key: "tasks/1/" + name}
+			assert.NoError(t, stores.Attachments.Create(ctx, &attachment))
+			assert.False(t, attachment.CreatedAt.IsZero())
+			attachments = append(attachments, attachment)
+		}
+
+		list, err := stores.Attachments.List(ctx, task.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 3) {
+			assert.Equal(t, "a.png", list[0].Filename)
+			assert.Equal(t, "tasks/1/a.png", list[0].Key)
+		}
+
+		_, err = stores.Attachments.Get(ctx, task.ID+1, attachments[0].ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		// Deleting an attachment queues its blob.
+		assert.NoError(t, stores.Attachments.Delete(ctx, &attachments[0]))
+		assert.ErrorIs(t, stores.Attachments.Delete(ctx, &attachments[0]), store.ErrNotFound)
+		keys, err := stores.Attachments.OrphanedBlobs(ctx, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"tasks/1/a.png"}, keys)
+
+		assert.NoError(t, stores.Attachments.ForgetBlobs(ctx, keys))
+		keys, _ = stores.Attachments.OrphanedBlobs(ctx, 10)
+		assert.Empty(t, keys)
+
+		// Trashing keeps the attachments; purging queues their blobs.
+		assert.NoError(t, stores.Tasks.Delete(ctx, &task, true))
+		keys, _ = stores.Attachments.OrphanedBlobs(ctx, 10)
+		assert.Empty(t, keys)
+
+		assert.NoError(t, stores.Tasks.Purge(ctx, &task))
+		list, _ = stores.Attachments.List(ctx, task.ID)
+		assert.Empty(t, list)
+		keys, _ = stores.Attachments.OrphanedBlobs(ctx, 10)
+		slices.Sort(keys)
+		assert.Equal(t, []string{"tasks/1/b.pdf", "tasks/1/c.txt"}, keys)
+		keys, _ = stores.Attachments.OrphanedBlobs(ctx, 1)
+		assert.Len(t, keys, 1)
+	})
+}
+
+func TestChecklistStore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		task := models.Task{Title: "task", UserID: 1, Version: 1}
+		assert.NoError(t, stores.Tasks.Create(ctx, &task))
+
+		var items []models.ChecklistItem
+		for _, text := range []string{"first", "second", "third"} {
+			item := models.ChecklistItem{TaskID: task.ID, Text: text}
+			assert.NoError(t, stores.Checklists.Create(ctx, &item))
+			items = append(items, item)
+		}
+		assert.Equal(t, 3, items[2].Position)
+
+		items[1].Checked = true
+		assert.NoError(t, stores.Checklists.Update(ctx, &items[1]))
+
+		got, _ := stores.Tasks.Get(ctx, 1, task.ID)
+		assert.Equal(t, models.ChecklistProgress{Checked: 1, Total: 3}, got.Checklist)
+		assert.Equal(t, uint(5), got.Version)
+		assert.False(t, got.Done)
+
+		ids := []uint{items[2].ID, items[0].ID, items[1].ID}
+		assert.NoError(t, stores.Checklists.Reorder(ctx, task.ID, ids))
+		list, err := stores.Checklists.List(ctx, task.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 3) {
+			assert.Equal(t, "third", list[0].Text)
+			assert.Equal(t, 1, list[0].Position)
+			assert.Equal(t, "second", list[2].Text)
+		}
+
+		assert.ErrorIs(t, stores.Checklists.Reorder(ctx, task.ID, ids[:2]), store.ErrInvalidOrder)
+		assert.ErrorIs(t, stores.Checklists.Reorder(ctx, task.ID, []uint{ids[0], ids[0], ids[1]}), store.ErrInvalidOrder)
+		assert.ErrorIs(t, stores.Checklists.Reorder(ctx, task.ID+1, ids), store.ErrInvalidOrder)
+
+		_, err = stores.Checklists.Get(ctx, task.ID+1, items[0].ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		assert.NoError(t, stores.Checklists.Delete(ctx, &items[0]))
+		assert.ErrorIs(t, stores.Checklists.Delete(ctx, &items[0]), store.ErrNotFound)
+
+		// Items are purged with their task.
+		assert.NoError(t, stores.Tasks.Delete(ctx, &task, true))
+		assert.NoError(t, stores.Tasks.Purge(ctx, &task))
+		list, _ = stores.Checklists.List(ctx, task.ID)
+		assert.Empty(t, list)
+	})
+}
+
+func TestChecklistAutoComplete(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		parent := models.Task{Title: "parent", UserID: 1, Version: 1, AutoComplete: true}
+		assert.NoError(t, stores.Tasks.Create(ctx, &parent))
+		task := models.Task{Title: "task", UserID: 1, Version: 1, ParentID: &parent.ID, ChecklistAutoComplete: true}
+		assert.NoError(t, stores.Tasks.Create(ctx, &task))
+
+		first := models.ChecklistItem{TaskID: task.ID, Text: "first"}
+		second := models.ChecklistItem{TaskID: task.ID, Text: "second"}
+		assert.NoError(t, stores.Checklists.Create(ctx, &first))
+		assert.NoError(t, stores.Checklists.Create(ctx, &second))
+
+		first.Checked = true
+		assert.NoError(t, stores.Checklists.Update(ctx, &first))
+		got, _ := stores.Tasks.Get(ctx, 1, task.ID)
+		assert.False(t, got.Done)
+
+		// Checking the last item completes the task and, through it, the
+		// auto-completing parent.
+		second.Checked = true
+		assert.NoError(t, stores.Checklists.Update(ctx, &second))
+		got, _ = stores.Tasks.Get(ctx, 1, task.ID)
+		assert.True(t, got.Done)
+		assert.NotNil(t, got.CompletedAt)
+		got, _ = stores.Tasks.Get(ctx, 1, parent.ID)
+		assert.True(t, got.Done)
+
+		history, _ := stores.Tasks.History(ctx, 1, task.ID)
+		if assert.Len(t, history, 2) {
+			assert.Equal(t, models.TaskChanges{"done": {From: false, To: true}}, history[1].Changes)
+		}
+
+		// Adding an unchecked item reopens it.
+		third := models.ChecklistItem{TaskID: task.ID, Text: "third"}
+		assert.NoError(t, stores.Checklists.Create(ctx, &third))
+		got, _ = stores.Tasks.Get(ctx, 1, task.ID)
+		assert.False(t, got.Done)
+		assert.Nil(t, got.CompletedAt)
+
+		// Deleting it completes the task again.
+		assert.NoError(t, stores.Checklists.Delete(ctx, &third))
+		got, _ = stores.Tasks.Get(ctx, 1, task.ID)
+		assert.True(t, got.Done)
+	})
+}
+
+func TestChecklistAutoCompleteRecurring(t *testing.T) {
+	forEachStore(t, func(t *testing.T, stores store.Stores) {
+		ctx := context.Background()
+
+		due := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+		task := models.Task{Title: "task", UserID: 1, Version: 1, ChecklistAutoComplete: true,
+			RRule: "FREQ=WEEKLY", RRuleStart: &due, DueAt: &due}
+		assert.NoError(t, stores.Tasks.Create(ctx, &task))
+
+		item := models.ChecklistItem{TaskID: task.ID, Text: "only"}
+		assert.NoError(t, stores.Checklists.Create(ctx, &item))
+
+		// Checking off every item would complete the task without moving
+		// it to its next occurrence, so it is left open.
+		item.Checked = true
+		assert.NoError(t, stores.Checklists.Update(ctx, &item))
+		got, _ := stores.Tasks.Get(ctx, 1, task.ID)
+		assert.False(t, got.Done)
+		assert.Nil(t, got.CompletedAt)
+		assert.Equal(t, due, got.DueAt.UTC())
+		assert.Equal(t, models.ChecklistProgress{Checked: 1, Total: 1}, got.Checklist)
+	})
+}
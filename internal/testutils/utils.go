@@ -1,22 +1,51 @@
 package testutils
 
 import (
-	"go-todo-api/internal/models"
+	"context"
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/db"
+	"go-todo-api/internal/utils"
 	"testing"
 
-	"gorm.io/driver/sqlite"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 func SetupTestDB(t *testing.T) *gorm.DB {
-	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	cfg := config.Default().Database
+	cfg.Driver = "sqlite"
+	cfg.Path = ":memory:"
+
+	testDB, err := db.ConnectDatabase(cfg)
 	if err != nil {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	if err := testDB.AutoMigrate(&models.Task{}, &models.User{}); err != nil {
+	migrator, err := db.NewMigrator(testDB)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if _, err := migrator.Up(context.Background()); err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
 
 	return testDB
 }
+
+// TestConfig returns the default configuration with a cheap bcrypt cost.
+func TestConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Auth.BcryptCost = bcrypt.MinCost
+	return cfg
+}
+
+// SetupTokenManager returns a token manager signing with an ephemeral key.
+func SetupTokenManager(t *testing.T) *utils.TokenManager {
+	keys, err := utils.NewEphemeralKeyManager()
+	if err != nil {
+		t.Fatalf("Failed to create signing keys: %v", err)
+	}
+
+	return utils.NewTokenManager(keys, TestConfig().Auth)
+}
@@ -0,0 +1,46 @@
+// Package trash purges deleted tasks once their retention period is over.
+package trash
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/store"
+)
+
+// Purger returns a worker that permanently deletes the tasks that have been
+// in the trash for longer than cfg.Retention. It runs once at start and
+// then every cfg.PurgeInterval.
+func Purger(tasks store.TaskStore, cfg config.TrashConfig) func(context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(cfg.PurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			Purge(ctx, tasks, cfg.Retention)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// Purge permanently deletes the tasks trashed more than retention ago.
+// Failures are logged and retried on the next run.
+func Purge(ctx context.Context, tasks store.TaskStore, retention time.Duration) {
+	purged, err := tasks.PurgeDeleted(ctx, time.Now().Add(-retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Error purging the trash: %v", err)
+		}
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d tasks from the trash", purged)
+	}
+}
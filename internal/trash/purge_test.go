@@ -0,0 +1,57 @@
+package trash_test
+
+import (
+	"context"
+	"go-todo-api/internal/config"
+	"go-todo-api/internal/models"
+	"go-todo-api/internal/store"
+	"go-todo-api/internal/trash"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	tasks := store.NewMemoryStores().Tasks
+
+	task := models.Task{Title: "Old", UserID: 1, Version: 1}
+	assert.NoError(t, tasks.Create(ctx, &task))
+	assert.NoError(t, tasks.Delete(ctx, &task, false))
+
+	trash.Purge(ctx, tasks, time.Hour)
+	trashed, _ := tasks.Trash(ctx, 1)
+	assert.Len(t, trashed, 1)
+
+	trash.Purge(ctx, tasks, -time.Hour)
+	trashed, _ = tasks.Trash(ctx, 1)
+	assert.Empty(t, trashed)
+}
+
+func TestPurgerRunsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tasks := store.NewMemoryStores().Tasks
+
+	done := make(chan struct{})
+	go func() {
+		trash.Purger(tasks, config.TrashConfig{Retention: -time.Hour, PurgeInterval: time.Millisecond})(ctx)
+		close(done)
+	}()
+
+	task := models.Task{Title: "Old", UserID: 1, Version: 1}
+	assert.NoError(t, tasks.Create(ctx, &task))
+	assert.NoError(t, tasks.Delete(ctx, &task, false))
+
+	assert.Eventually(t, func() bool {
+		trashed, _ := tasks.Trash(ctx, 1)
+		return len(trashed) == 0
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Purger did not stop after cancellation")
+	}
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	doc := `{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`
+	patch := `{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`
+
+	result, err := MergePatch([]byte(doc), []byte(patch))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`, string(result))
+
+	_, err = MergePatch([]byte(doc), []byte(`{invalid`))
+	assert.Error(t, err)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := `{"title":"a","done":false,"list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`
+
+	cases := []struct {
+		patch    string
+		expected string
+	}{
+		{`[{"op":"replace","path":"/title","value":"b"}]`, `{"title":"b","done":false,"list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"add","path":"/list/1","value":9}]`, `{"title":"a","done":false,"list":[1,9,2,3],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"add","path":"/list/-","value":4}]`, `{"title":"a","done":false,"list":[1,2,3,4],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"remove","path":"/list/0"}]`, `{"title":"a","done":false,"list":[2,3],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"remove","path":"/nested/a~1b"},{"op":"remove","path":"/nested/m~0n"}]`, `{"title":"a","done":false,"list":[1,2,3],"nested":{}}`},
+		{`[{"op":"move","from":"/title","path":"/name"}]`, `{"name":"a","done":false,"list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"copy","from":"/list","path":"/copy"}]`, `{"title":"a","done":false,"list":[1,2,3],"copy":[1,2,3],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"test","path":"/done","value":false},{"op":"replace","path":"/done","value":true}]`, `{"title":"a","done":true,"list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"replace","path":"/title","value":null},{"op":"test","path":"/title","value":null}]`, `{"title":null,"done":false,"list":[1,2,3],"nested":{"a/b":1,"m~n":2}}`},
+		{`[{"op":"add","path":"/list/0","value":null}]`, `{"title":"a","done":false,"list":[null,1,2,3],"nested":{"a/b":1,"m~n":2}}`},
+	}
+
+	for _, tc := range cases {
+		result, err := ApplyJSONPatch([]byte(doc), []byte(tc.patch))
+		if assert.NoError(t, err, tc.patch) {
+			assert.JSONEq(t, tc.expected, string(result), tc.patch)
+		}
+	}
+}
+
+func TestApplyJSONPatchErrors(t *testing.T) {
+	doc := `{"title":"a","list":[1]}`
+
+	_, err := ApplyJSONPatch([]byte(doc), []byte(`[{"op":"test","path":"/title","value":"b"}]`))
+	assert.ErrorIs(t, err, ErrPatchTestFailed)
+
+	invalid := []string{
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"add","path":"/list/5","value":1}]`,
+		`[{"op":"add","path":"/title"}]`,
+		`[{"op":"move","from":"/list","path":"/list/0"}]`,
+		`[{"op":"explode","path":"/title"}]`,
+		`[{"op":"add","path":"title","value":1}]`,
+		`{"op":"add"}`,
+	}
+
+	for _, patch := range invalid {
+		_, err := ApplyJSONPatch([]byte(doc), []byte(patch))
+		assert.Error(t, err, patch)
+	}
+}
@@ -1,53 +1,121 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"time"
 
+	"go-todo-api/internal/config"
+
 	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var BcryptCost = bcrypt.DefaultCost
-var JwtKey = []byte("your-secret-key-here")
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrWrongTokenType = errors.New("wrong token type")
+)
 
 type Claims struct {
-	UserID uint `json:"user_id"`
+	UserID    uint   `json:"user_id"`
+	TokenType string `json:"token_type"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID uint) (string, error) {
-	expirationTime := time.Now().Add(30 * time.Minute)
+// TokenManager issues and verifies the API's access and refresh tokens.
+type TokenManager struct {
+	Keys            *KeyManager
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
 
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+func NewTokenManager(keys *KeyManager, cfg config.AuthConfig) *TokenManager {
+	return &TokenManager{
+		Keys:            keys,
+		Issuer:          cfg.Issuer,
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		RefreshTokenTTL: cfg.RefreshTokenTTL,
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JwtKey)
+func (m *TokenManager) GenerateAccessToken(userID uint) (string, error) {
+	return m.generateToken(userID, TokenTypeAccess, m.AccessTokenTTL)
 }
 
 // Gera um Refresh Token com expiração maior (ex: 7 dias)
-func GenerateRefreshToken(userID uint) (string, error) {
-	expirationTime := time.Now().Add(7 * 24 * time.Hour)
+func (m *TokenManager) GenerateRefreshToken(userID uint) (string, error) {
+	return m.generateToken(userID, TokenTypeRefresh, m.RefreshTokenTTL)
+}
+
+// generateToken signs a token of the given type. The audience mirrors the
+// token type so that other verifiers relying only on registered claims can
+// still tell the two kinds apart.
+func (m *TokenManager) generateToken(userID uint, tokenType string, ttl time.Duration) (string, error) {
+	tokenID, err := NewTokenID()
+	if err != nil {
+		return "", err
+	}
 
+	now := time.Now()
 	claims := &Claims{
-		UserID: userID,
+		UserID:    userID,
+		TokenType: tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			Issuer:    m.Issuer,
+			Audience:  jwt.ClaimStrings{tokenType},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JwtKey)
+	return m.Keys.Sign(claims)
+}
+
+// ParseToken verifies the signature, expiry, issuer and type of a token.
+func (m *TokenManager) ParseToken(tokenString, tokenType string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.Keys.Keyfunc, jwt.WithValidMethods(m.Keys.ValidMethods()))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || claims.ID == "" || !claims.VerifyIssuer(m.Issuer, true) {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.TokenType != tokenType || !claims.VerifyAudience(tokenType, true) {
+		return nil, ErrWrongTokenType
+	}
+
+	return claims, nil
+}
+
+// NewTokenID returns a random identifier suitable for token IDs and families.
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token for storage.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+func HashPassword(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	if err != nil {
 		return "", err
 	}
@@ -4,12 +4,25 @@ import (
 	"testing"
 	"time"
 
+	"go-todo-api/internal/config"
+
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
+func newTestTokenManager(t *testing.T) *TokenManager {
+	keys, err := NewEphemeralKeyManager()
+	if err != nil {
+		t.Fatalf("Error creating keys: %v", err)
+	}
+	return NewTokenManager(keys, config.Default().Auth)
+}
+
 func TestGenerateAccessToken(t *testing.T) {
-	tokenString, err := GenerateAccessToken(123)
+	tokens := newTestTokenManager(t)
+
+	tokenString, err := tokens.GenerateAccessToken(123)
 	if err != nil {
 		t.Fatalf("Error generating token: %v", err)
 	}
@@ -34,7 +47,9 @@ func TestGenerateAccessToken(t *testing.T) {
 }
 
 func TestGenerateRefreshToken(t *testing.T) {
-	tokenString, err := GenerateRefreshToken(1234)
+	tokens := newTestTokenManager(t)
+
+	tokenString, err := tokens.GenerateRefreshToken(1234)
 	if err != nil {
 		t.Fatalf("Error generating refresh token")
 	}
@@ -61,7 +76,7 @@ func TestGenerateRefreshToken(t *testing.T) {
 func TestHashAndCheckPassword(t *testing.T) {
 	password := "mySecurePassword"
 
-	hash, err := HashPassword(password)
+	hash, err := HashPassword(password, bcrypt.MinCost)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, hash)
 
@@ -69,10 +84,67 @@ func TestHashAndCheckPassword(t *testing.T) {
 
 	assert.False(t, CheckPasswordHash("wrongPassword", hash))
 
-	originalCost := BcryptCost
-	BcryptCost = 100
-	defer func() { BcryptCost = originalCost }()
-
-	_, err = HashPassword("any")
+	_, err = HashPassword("any", 100)
 	assert.Error(t, err)
 }
+
+func TestNewTokenIDAndHashToken(t *testing.T) {
+	first, err := NewTokenID()
+	assert.NoError(t, err)
+	second, err := NewTokenID()
+	assert.NoError(t, err)
+
+	assert.Len(t, first, 32)
+	assert.NotEqual(t, first, second)
+
+	assert.Equal(t, HashToken("token"), HashToken("token"))
+	assert.NotEqual(t, HashToken("token"), HashToken("other"))
+}
+
+func TestParseTokenRejectsWrongType(t *testing.T) {
+	tokens := newTestTokenManager(t)
+
+	access, err := tokens.GenerateAccessToken(1)
+	assert.NoError(t, err)
+	refresh, err := tokens.GenerateRefreshToken(1)
+	assert.NoError(t, err)
+
+	claims, err := tokens.ParseToken(access, TokenTypeAccess)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+	assert.NotEmpty(t, claims.ID)
+
+	_, err = tokens.ParseToken(refresh, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+
+	_, err = tokens.ParseToken(access, TokenTypeRefresh)
+	assert.ErrorIs(t, err, ErrWrongTokenType)
+}
+
+func TestParseTokenRejectsForeignIssuer(t *testing.T) {
+	tokens := newTestTokenManager(t)
+
+	claims := &Claims{
+		UserID:    1,
+		TokenType: TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "id",
+			Issuer:    "someone-else",
+			Audience:  jwt.ClaimStrings{TokenTypeAccess},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+	tokenString, err := tokens.Keys.Sign(claims)
+	assert.NoError(t, err)
+
+	_, err = tokens.ParseToken(tokenString, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	claims.Issuer = tokens.Issuer
+	claims.ID = ""
+	tokenString, err = tokens.Keys.Sign(claims)
+	assert.NoError(t, err)
+
+	_, err = tokens.ParseToken(tokenString, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
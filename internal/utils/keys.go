@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"go-todo-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey is a single key known to the KeyManager. Keys loaded only for
+// verification have no private part.
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	Secret     []byte
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeyManager signs tokens with its active key and verifies them against the
+// active key plus any retiring keys, selected by the token's kid header.
+type KeyManager struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// LoadKeyManager builds a KeyManager from cfg.
+func LoadKeyManager(cfg config.JWTConfig) (*KeyManager, error) {
+	var active *SigningKey
+	var err error
+
+	switch {
+	case cfg.PrivateKeyFile != "" && (cfg.Secret != "" || cfg.SecretFile != ""):
+		return nil, errors.New("configure either a secret or a private key file, not both")
+	case cfg.PrivateKeyFile != "":
+		active, err = loadKeyFile(cfg.PrivateKeyFile)
+		if err == nil && active.PrivateKey == nil {
+			err = fmt.Errorf("%s does not contain a private key", cfg.PrivateKeyFile)
+		}
+	case cfg.SecretFile != "":
+		var secret []byte
+		secret, err = os.ReadFile(cfg.SecretFile)
+		if err == nil {
+			active, err = NewHMACKey(strings.TrimSpace(string(secret)))
+		}
+	case cfg.Secret != "":
+		active, err = NewHMACKey(cfg.Secret)
+	default:
+		return nil, errors.New("no signing key configured")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.KeyID != "" {
+		active.ID = cfg.KeyID
+	}
+
+	var retiring []*SigningKey
+	for _, secret := range cfg.RetiringSecrets {
+		key, err := NewHMACKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		retiring = append(retiring, key)
+	}
+	for _, path := range cfg.RetiringKeyFiles {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		retiring = append(retiring, key)
+	}
+
+	return NewKeyManager(active, retiring...)
+}
+
+// NewKeyManager returns a manager signing with active. The active key must
+// have private material; retiring keys only need a public key or secret.
+func NewKeyManager(active *SigningKey, retiring ...*SigningKey) (*KeyManager, error) {
+	if active == nil || (active.Secret == nil && active.PrivateKey == nil) {
+		return nil, errors.New("active key cannot sign")
+	}
+
+	m := &KeyManager{active: active, keys: map[string]*SigningKey{}}
+	for _, key := range append([]*SigningKey{active}, retiring...) {
+		if _, exists := m.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		m.keys[key.ID] = key
+	}
+
+	return m, nil
+}
+
+// NewEphemeralKeyManager signs with a random HS256 key, for tests and local
+// runs without configured keys. Its tokens do not survive a restart.
+func NewEphemeralKeyManager() (*KeyManager, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	key, err := NewHMACKey(string(secret))
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyManager(key)
+}
+
+// NewHMACKey returns an HS256 key. Its id is derived from a hash of the
+// secret so that every replica computes the same kid.
+func NewHMACKey(secret string) (*SigningKey, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("HS256 secrets must be at least 32 bytes long")
+	}
+
+	sum := sha256.Sum256([]byte("kid:" + secret))
+	return &SigningKey{
+		ID:        hex.EncodeToString(sum[:8]),
+		Algorithm: AlgHS256,
+		Secret:    []byte(secret),
+	}, nil
+}
+
+// NewAsymmetricKey wraps an RSA or Ed25519 key, which may be either the
+// private key or only the public half. The kid is the RFC 7638 thumbprint.
+func NewAsymmetricKey(key interface{}) (*SigningKey, error) {
+	signingKey := &SigningKey{}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signingKey.Algorithm, signingKey.PrivateKey, signingKey.PublicKey = AlgRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		signingKey.Algorithm, signingKey.PublicKey = AlgRS256, k
+	case ed25519.PrivateKey:
+		signingKey.Algorithm, signingKey.PrivateKey, signingKey.PublicKey = AlgEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		signingKey.Algorithm, signingKey.PublicKey = AlgEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	// RFC 7638 hashes the required members in lexicographic order, which is
+	// what json.Marshal produces for a map.
+	jwk := signingKey.jwk()
+	members := map[string]string{"kty": jwk.Kty}
+	if jwk.Kty == "RSA" {
+		members["n"], members["e"] = jwk.N, jwk.E
+	} else {
+		members["crv"], members["x"] = jwk.Crv, jwk.X
+	}
+
+	thumbprint, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(thumbprint)
+	signingKey.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return signingKey, nil
+}
+
+func loadKeyFile(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return NewAsymmetricKey(key)
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// Sign signs claims with the active key and stamps its kid in the header.
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(signingMethod(m.active.Algorithm), claims)
+	token.Header["kid"] = m.active.ID
+
+	if m.active.Algorithm == AlgHS256 {
+		return token.SignedString(m.active.Secret)
+	}
+	return token.SignedString(m.active.PrivateKey)
+}
+
+// Keyfunc resolves the verification key for a token from its kid header and
+// rejects tokens whose alg does not match the key.
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	if key.Algorithm == AlgHS256 {
+		return key.Secret, nil
+	}
+	return key.PublicKey, nil
+}
+
+// ValidMethods lists the algorithms of every key known to the manager.
+func (m *KeyManager) ValidMethods() []string {
+	seen := map[string]bool{}
+	var methods []string
+	for _, key := range m.keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			methods = append(methods, key.Algorithm)
+		}
+	}
+	return methods
+}
+
+// JWK is a public key in RFC 7517 format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of the active and retiring asymmetric keys.
+// HS256 secrets are never published.
+func (m *KeyManager) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range m.orderedKeys() {
+		if key.Algorithm == AlgHS256 {
+			continue
+		}
+		jwk := key.jwk()
+		jwk.Kid, jwk.Alg, jwk.Use = key.ID, key.Algorithm, "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// orderedKeys returns the active key first, followed by the retiring keys
+// sorted by id.
+func (m *KeyManager) orderedKeys() []*SigningKey {
+	var retiring []*SigningKey
+	for id, key := range m.keys {
+		if id != m.active.ID {
+			retiring = append(retiring, key)
+		}
+	}
+	sort.Slice(retiring, func(i, j int) bool { return retiring[i].ID < retiring[j].ID })
+
+	return append([]*SigningKey{m.active}, retiring...)
+}
+
+// jwk renders the key type specific members of the public key.
+func (k *SigningKey) jwk() JWK {
+	var jwk JWK
+
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return jwk
+}
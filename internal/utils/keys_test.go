@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-todo-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func testClaims() *Claims {
+	return &Claims{
+		UserID:    7,
+		TokenType: TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "id",
+			Issuer:    config.Default().Auth.Issuer,
+			Audience:  jwt.ClaimStrings{TokenTypeAccess},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func tokensWithKeys(m *KeyManager) *TokenManager {
+	return NewTokenManager(m, config.Default().Auth)
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), strings.ReplaceAll(strings.ToLower(blockType), " ", "_")+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Error writing key file: %v", err)
+	}
+	return path
+}
+
+func TestKeyManagerAsymmetricAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	for _, private := range []interface{}{rsaKey, edKey} {
+		key, err := NewAsymmetricKey(private)
+		assert.NoError(t, err)
+		m, err := NewKeyManager(key)
+		assert.NoError(t, err)
+		tokens := tokensWithKeys(m)
+
+		tokenString, err := m.Sign(testClaims())
+		assert.NoError(t, err)
+
+		token, _, err := new(jwt.Parser).ParseUnverified(tokenString, &Claims{})
+		assert.NoError(t, err)
+		assert.Equal(t, key.ID, token.Header["kid"])
+		assert.Equal(t, key.Algorithm, token.Header["alg"])
+
+		claims, err := tokens.ParseToken(tokenString, TokenTypeAccess)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(7), claims.UserID)
+	}
+}
+
+func TestKeyManagerRotation(t *testing.T) {
+	oldKey, err := NewHMACKey(strings.Repeat("o", 32))
+	assert.NoError(t, err)
+	newKey, err := NewHMACKey(strings.Repeat("n", 32))
+	assert.NoError(t, err)
+
+	before, err := NewKeyManager(oldKey)
+	assert.NoError(t, err)
+	oldToken, err := before.Sign(testClaims())
+	assert.NoError(t, err)
+
+	after, err := NewKeyManager(newKey, oldKey)
+	assert.NoError(t, err)
+	_, err = tokensWithKeys(after).ParseToken(oldToken, TokenTypeAccess)
+	assert.NoError(t, err)
+
+	newToken, err := after.Sign(testClaims())
+	assert.NoError(t, err)
+	token, _, _ := new(jwt.Parser).ParseUnverified(newToken, &Claims{})
+	assert.Equal(t, newKey.ID, token.Header["kid"])
+
+	retired, err := NewKeyManager(newKey)
+	assert.NoError(t, err)
+	_, err = tokensWithKeys(retired).ParseToken(oldToken, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestKeyManagerRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, err := NewAsymmetricKey(rsaKey)
+	assert.NoError(t, err)
+	m, err := NewKeyManager(key)
+	assert.NoError(t, err)
+	// An HS256 token "signed" with the RSA public key must not verify.
+	publicDER := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = key.ID
+	forged, err := token.SignedString(publicDER)
+	assert.NoError(t, err)
+
+	_, err = tokensWithKeys(m).ParseToken(forged, TokenTypeAccess)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestLoadKeyManagerFromFiles(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.NoError(t, err)
+
+	m, err := LoadKeyManager(config.JWTConfig{
+		PrivateKeyFile:   writePEM(t, "PRIVATE KEY", privateDER),
+		RetiringKeyFiles: []string{writePEM(t, "PUBLIC KEY", publicDER)},
+		RetiringSecrets:  []string{strings.Repeat("s", 32)},
+	})
+	assert.NoError(t, err)
+
+	jwks := m.JWKS()
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+		assert.Equal(t, AlgEdDSA, jwks.Keys[0].Alg)
+		assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+		assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	}
+
+	_, err = LoadKeyManager(config.JWTConfig{PrivateKeyFile: writePEM(t, "PUBLIC KEY", publicDER)})
+	assert.Error(t, err)
+
+	_, err = LoadKeyManager(config.JWTConfig{Secret: "short"})
+	assert.Error(t, err)
+
+	_, err = LoadKeyManager(config.JWTConfig{})
+	assert.Error(t, err)
+}
+
+func TestRFC7638Thumbprint(t *testing.T) {
+	// Test vector from RFC 8037 appendix A.3.
+	public, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	assert.NoError(t, err)
+
+	key, err := NewAsymmetricKey(ed25519.PublicKey(public))
+	assert.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", key.ID)
+}